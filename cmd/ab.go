@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
+)
+
+// abConfidence is the confidence level of the intervals reported for A/B deltas.
+const abConfidence = 0.95
+
+// ABResult compares endpoint B against endpoint A, level by level.
+type ABResult struct {
+	BaseURL   string    `json:"base_url" yaml:"base-url"`
+	ModelName string    `json:"model_name" yaml:"model-name"`
+	Latency   float64   `json:"latency" yaml:"latency"`
+	Rounds    int       `json:"rounds" yaml:"rounds"`
+	Levels    []ABLevel `json:"levels" yaml:"levels"`
+}
+
+// ABLevel holds every round measured at one level and the resulting deltas.
+type ABLevel struct {
+	Concurrency int                 `json:"concurrency" yaml:"concurrency"`
+	Rate        float64             `json:"rate,omitempty" yaml:"rate,omitempty"`
+	Metrics     []ABMetric          `json:"metrics" yaml:"metrics"`
+	A           []utils.SpeedResult `json:"a" yaml:"a"`
+	B           []utils.SpeedResult `json:"b" yaml:"b"`
+}
+
+// ABMetric is the B minus A delta of one metric, averaged over the rounds.
+type ABMetric struct {
+	Metric           string `json:"metric" yaml:"metric"`
+	utils.Comparison `yaml:",inline"`
+}
+
+type abMetric struct {
+	key   string
+	title string
+	value func(utils.SpeedResult) float64
+}
+
+// abMetrics are the metrics compared between the two endpoints.
+var abMetrics = []abMetric{
+	{"gen_tps", "Gen TPS", func(result utils.SpeedResult) float64 { return result.GenerationSpeed }},
+	{"prompt_tps", "Prompt TPS", func(result utils.SpeedResult) float64 { return result.PromptThroughput }},
+	{"request_throughput", "Req/s", func(result utils.SpeedResult) float64 { return result.RequestThroughput }},
+	{"ttft_p50", "TTFT P50(s)", func(result utils.SpeedResult) float64 { return result.Ttft.Median }},
+	{"e2e_p50", "E2E P50(s)", func(result utils.SpeedResult) float64 { return result.E2eLatency.Median }},
+	{"tpot_p50", "TPOT P50(ms)", func(result utils.SpeedResult) float64 { return result.Tpot.Median }},
+	{"success_rate", "Success", func(result utils.SpeedResult) float64 { return result.SuccessRate }},
+}
+
+// newABBenchmarks prepares the two sides of an A/B comparison: A is the
+// endpoint in options, B the one given by --ab-base-url and --ab-model.
+func newABBenchmarks(options Options) (*Benchmark, *Benchmark, error) {
+	if options.AbRounds < 2 {
+		return nil, nil, fmt.Errorf("--ab-rounds must be at least 2")
+	}
+	if options.Search {
+		return nil, nil, fmt.Errorf("--ab-base-url cannot be combined with --search")
+	}
+	if options.Repeat > 1 || options.RepeatCi > 0 {
+		return nil, nil, fmt.Errorf("--ab-base-url cannot be combined with --repeat or --repeat-ci, use --ab-rounds")
+	}
+	if options.Charts {
+		return nil, nil, fmt.Errorf("--ab-base-url cannot be combined with --charts")
+	}
+	benchmark, err := newBenchmark(options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	otherOptions := options
+	otherOptions.BaseURL = options.AbBaseURL
+	otherOptions.Model = options.AbModel
+	if otherOptions.Model == "" {
+		otherOptions.Model = options.Model
+	}
+	other, err := newBenchmark(otherOptions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("B endpoint: %v", err)
+	}
+	// Both sides must draw the same prompts, lengths and arrivals
+	other.Seed = benchmark.Seed
+	benchmark.SeedPrompts = true
+	other.SeedPrompts = true
+	return benchmark, other, nil
+}
+
+// runAB measures every level on benchmark (A) and other (B) in interleaved
+// rounds. Both use the same seed, so each round sends both endpoints the same
+// prompts, and the order alternates (ABBA...) so drift over time hits both sides
+// equally. In CLI mode the deltas are printed as each level completes.
+func (benchmark *Benchmark) runAB(other *Benchmark, rounds int, cli bool) (BenchmarkResult, error) {
+	latency, err := utils.MeasureLatency(benchmark.BaseURL, 5)
+	if err != nil {
+		return BenchmarkResult{}, fmt.Errorf("error testing latency of %s: %v", benchmark.BaseURL, err)
+	}
+	otherLatency, err := utils.MeasureLatency(other.BaseURL, 5)
+	if err != nil {
+		return BenchmarkResult{}, fmt.Errorf("error testing latency of %s: %v", other.BaseURL, err)
+	}
+
+	bold := "\033[1m"
+	green := "\033[32m"
+	reset := "\033[0m"
+	openLoop := len(benchmark.Rates) > 0
+	if cli {
+		utils.PrintBenchmarkHeader(benchmark.ModelName, benchmark.InputTokens, benchmark.MaxTokens, latency)
+		fmt.Printf("%s%sA:%s%s %s at %s (%.2f ms)%s\n", green, bold, reset, green, benchmark.ModelName, benchmark.BaseURL, latency, reset)
+		fmt.Printf("%s%sB:%s%s %s at %s (%.2f ms)%s\n", green, bold, reset, green, other.ModelName, other.BaseURL, otherLatency, reset)
+		fmt.Printf("%s%d interleaved rounds per level, deltas are B - A with %g%% confidence intervals (* excludes zero)%s\n\n",
+			green, rounds, abConfidence*100, reset)
+	}
+
+	ab := &ABResult{BaseURL: other.BaseURL, ModelName: other.ModelName, Latency: otherLatency, Rounds: rounds}
+	for _, level := range benchmark.levels() {
+		abLevel := ABLevel{Concurrency: level.Concurrency, Rate: level.Rate}
+		for round := 0; round < rounds; round++ {
+			sides := []*Benchmark{benchmark, other}
+			if round%2 == 1 {
+				sides = []*Benchmark{other, benchmark}
+			}
+			for _, side := range sides {
+				sideLatency := latency
+				if side == other {
+					sideLatency = otherLatency
+				}
+				result, err := side.measureSpeed(sideLatency, level, true)
+				if err != nil {
+					return BenchmarkResult{}, fmt.Errorf("%v, round %d at %s: %v", level, round+1, side.BaseURL, err)
+				}
+				if side == other {
+					abLevel.B = append(abLevel.B, result)
+				} else {
+					abLevel.A = append(abLevel.A, result)
+				}
+			}
+		}
+
+		for _, metric := range abMetrics {
+			if metric.key == "request_throughput" && !openLoop {
+				continue
+			}
+			var a, b []float64
+			for i := range abLevel.A {
+				a = append(a, metric.value(abLevel.A[i]))
+				b = append(b, metric.value(abLevel.B[i]))
+			}
+			abLevel.Metrics = append(abLevel.Metrics, ABMetric{Metric: metric.key, Comparison: utils.Compare(a, b, abConfidence)})
+		}
+		ab.Levels = append(ab.Levels, abLevel)
+
+		if cli {
+			printABLevel(level, abLevel)
+		}
+	}
+	if cli {
+		fmt.Println("\n" + "\033[36m" + strings.Repeat("=", 80) + "\033[0m")
+	}
+
+	result := benchmark.result(latency, nil, nil)
+	result.AB = ab
+	return result, nil
+}
+
+// printABLevel prints the deltas measured at one level.
+func printABLevel(level Level, abLevel ABLevel) {
+	bold := "\033[1m"
+	green := "\033[32m"
+	reset := "\033[0m"
+
+	header := "|    Metric    |      A      |      B      |    Delta    | Delta % |          95% CI          |"
+	separator := "|:-------------|:-----------:|:-----------:|:-----------:|:-------:|:------------------------:|"
+	fmt.Printf("%s%sAt %v%s\n", green, bold, level, reset)
+	fmt.Printf("%s%s%s%s\n", green, bold, header, reset)
+	fmt.Printf("%s%s%s\n", green, separator, reset)
+	for _, metric := range abLevel.Metrics {
+		title := metric.Metric
+		for _, known := range abMetrics {
+			if known.key == metric.Metric {
+				title = known.title
+			}
+		}
+		mark := " "
+		if metric.Significant() {
+			mark = "*"
+		}
+		interval := fmt.Sprintf("[%.2f, %.2f]", metric.CiLow, metric.CiHigh)
+		fmt.Printf("%s| %-12s | %11.2f | %11.2f | %+11.2f | %+6.2f%% | %23s%s |%s\n",
+			green, title, metric.A, metric.B, metric.Delta, metric.DeltaPercent, interval, mark, reset)
+	}
+	fmt.Printf("%s%s%s\n\n", green, separator, reset)
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
+)
+
+// Assertion is a threshold checked against each measured level, parsed from
+// "[conc=N:|rate=R:]metric op value", e.g. "conc=16:gen_tps>=400" or "p95_ttft<1.5s".
+type Assertion struct {
+	Text       string
+	Level      *Level // Only this level is checked when set
+	Metric     string
+	Percentile float64 // Percentile a distribution metric is checked at, 0 for mean/median
+	Op         string
+	Value      float64
+	value      func(utils.SpeedResult) (float64, bool)
+}
+
+// AssertionCheck is the outcome of an assertion at one level.
+type AssertionCheck struct {
+	Assertion string
+	Benchmark string
+	Level     string
+	Passed    bool
+	Message   string
+}
+
+var assertionPattern = regexp.MustCompile(`^(?:(conc|rate)=([0-9.]+):)?([a-z0-9_.]+)\s*(>=|<=|==|>|<)\s*(.+)$`)
+
+// assertionScalars are the metrics read directly from a SpeedResult, with the
+// unit their thresholds are expressed in.
+var assertionScalars = map[string]struct {
+	unit  string
+	value func(utils.SpeedResult) (float64, bool)
+}{
+	"gen_tps":        {"", func(result utils.SpeedResult) (float64, bool) { return result.GenerationSpeed, true }},
+	"prompt_tps":     {"", func(result utils.SpeedResult) (float64, bool) { return result.PromptThroughput, true }},
+	"req_per_sec":    {"", func(result utils.SpeedResult) (float64, bool) { return result.RequestThroughput, true }},
+	"success_rate":   {"%", func(result utils.SpeedResult) (float64, bool) { return result.SuccessRate, true }},
+	"min_ttft":       {"s", func(result utils.SpeedResult) (float64, bool) { return result.MinTtft, !result.Ttft.Empty() }},
+	"max_ttft":       {"s", func(result utils.SpeedResult) (float64, bool) { return result.MaxTtft, !result.Ttft.Empty() }},
+	"duration":       {"s", func(result utils.SpeedResult) (float64, bool) { return result.Duration, true }},
+	"max_stall":      {"ms", func(result utils.SpeedResult) (float64, bool) { return result.MaxStall, !result.Ttft.Empty() }},
+	"goodput_rps":    {"", goodputValue(func(goodput *utils.Goodput) float64 { return goodput.RequestRate })},
+	"goodput_tps":    {"", goodputValue(func(goodput *utils.Goodput) float64 { return goodput.TokenRate })},
+	"slo_attainment": {"%", goodputValue(func(goodput *utils.Goodput) float64 { return goodput.Attainment })},
+}
+
+// assertionDistributions are the per-request distributions that can be checked
+// at their mean, median or a percentile, e.g. "p95_ttft" or "mean_tpot".
+var assertionDistributions = map[string]struct {
+	unit  string
+	value func(utils.SpeedResult) utils.Distribution
+}{
+	"ttft":     {"s", func(result utils.SpeedResult) utils.Distribution { return result.Ttft }},
+	"e2e":      {"s", func(result utils.SpeedResult) utils.Distribution { return result.E2eLatency }},
+	"tpot":     {"ms", func(result utils.SpeedResult) utils.Distribution { return result.Tpot }},
+	"itl":      {"ms", func(result utils.SpeedResult) utils.Distribution { return result.Itl }},
+	"user_tps": {"", func(result utils.SpeedResult) utils.Distribution { return result.UserSpeed }},
+}
+
+func goodputValue(get func(*utils.Goodput) float64) func(utils.SpeedResult) (float64, bool) {
+	return func(result utils.SpeedResult) (float64, bool) {
+		if result.Goodput == nil {
+			return 0, false
+		}
+		return get(result.Goodput), true
+	}
+}
+
+// ParseAssertion parses an assertion such as "conc=16:gen_tps>=400". Latency
+// thresholds may carry a duration unit ("1.5s", "200ms") and rates a percent sign.
+// Latencies of a level without successful requests are not measured, which
+// fails the assertion.
+func ParseAssertion(text string) (Assertion, error) {
+	match := assertionPattern.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return Assertion{}, fmt.Errorf("invalid assertion %q, expected [conc=N:|rate=R:]metric op value", text)
+	}
+	assertion := Assertion{Text: strings.TrimSpace(text), Metric: match[3], Op: match[4]}
+
+	if match[1] != "" {
+		number, err := strconv.ParseFloat(match[2], 64)
+		if err != nil || number <= 0 || (match[1] == "conc" && number != math.Trunc(number)) {
+			return Assertion{}, fmt.Errorf("invalid level in assertion %q", text)
+		}
+		if match[1] == "conc" {
+			assertion.Level = &Level{Concurrency: int(number)}
+		} else {
+			assertion.Level = &Level{Rate: number}
+		}
+	}
+
+	unit := ""
+	if scalar, ok := assertionScalars[assertion.Metric]; ok {
+		unit, assertion.value = scalar.unit, scalar.value
+	} else {
+		stat, name, found := strings.Cut(assertion.Metric, "_")
+		distribution, ok := assertionDistributions[name]
+		if !found || !ok {
+			// Distributions without a statistic are checked at their median
+			stat, name = "median", assertion.Metric
+			distribution, ok = assertionDistributions[name]
+		}
+		if !ok {
+			return Assertion{}, fmt.Errorf("unknown metric %q in assertion %q", assertion.Metric, text)
+		}
+		switch {
+		case stat == "mean":
+			assertion.value = func(result utils.SpeedResult) (float64, bool) {
+				values := distribution.value(result)
+				return values.Mean, !values.Empty()
+			}
+		case stat == "median" || stat == "p50":
+			assertion.value = func(result utils.SpeedResult) (float64, bool) {
+				values := distribution.value(result)
+				return values.Median, !values.Empty()
+			}
+		case strings.HasPrefix(stat, "p"):
+			p, err := strconv.ParseFloat(stat[1:], 64)
+			if err != nil || p <= 0 || p > 100 {
+				return Assertion{}, fmt.Errorf("invalid percentile %q in assertion %q", stat, text)
+			}
+			assertion.Percentile = p
+			key := utils.PercentileKey(p)
+			assertion.value = func(result utils.SpeedResult) (float64, bool) {
+				value, ok := distribution.value(result).Percentiles[key]
+				return value, ok
+			}
+		default:
+			return Assertion{}, fmt.Errorf("unknown statistic %q in assertion %q", stat, text)
+		}
+		unit = distribution.unit
+	}
+
+	value, err := parseThreshold(match[5], unit)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("invalid value in assertion %q: %v", text, err)
+	}
+	assertion.Value = value
+	return assertion, nil
+}
+
+// parseThreshold parses a threshold into the metric's unit: seconds ("s"),
+// milliseconds ("ms"), a fraction ("%") or a plain number (""). Plain numbers
+// above 1 are rejected for fractions, as they were likely meant as percentages.
+func parseThreshold(text string, unit string) (float64, error) {
+	text = strings.TrimSpace(text)
+	if number, err := strconv.ParseFloat(text, 64); err == nil {
+		if unit == "%" && number > 1 {
+			return 0, fmt.Errorf("fractions range from 0 to 1, write %s%% for a percentage", text)
+		}
+		return number, nil
+	}
+	switch unit {
+	case "s", "ms":
+		duration, err := time.ParseDuration(text)
+		if err != nil {
+			return 0, err
+		}
+		if unit == "ms" {
+			return float64(duration) / float64(time.Millisecond), nil
+		}
+		return duration.Seconds(), nil
+	case "%":
+		if number, found := strings.CutSuffix(text, "%"); found {
+			percent, err := strconv.ParseFloat(number, 64)
+			return percent / 100, err
+		}
+	}
+	return 0, fmt.Errorf("%q is not a number", text)
+}
+
+// Check evaluates the assertion against every level of results it applies to.
+func (assertion Assertion) Check(benchmark string, results []utils.SpeedResult) []AssertionCheck {
+	var checks []AssertionCheck
+	for _, result := range results {
+		level := Level{Concurrency: result.Concurrency, Rate: result.Rate}
+		if result.Rate > 0 {
+			// Open-loop results report the peak concurrency, not a level
+			level.Concurrency = 0
+		}
+		if assertion.Level != nil && *assertion.Level != level {
+			continue
+		}
+
+		check := AssertionCheck{Assertion: assertion.Text, Benchmark: benchmark, Level: level.String()}
+		value, ok := assertion.value(result)
+		switch {
+		case !ok:
+			check.Message = fmt.Sprintf("%s was not measured", assertion.Metric)
+		case assertion.holds(value):
+			check.Passed = true
+			check.Message = fmt.Sprintf("%s = %g", assertion.Metric, value)
+		default:
+			check.Message = fmt.Sprintf("%s = %g, want %s %g", assertion.Metric, value, assertion.Op, assertion.Value)
+		}
+		checks = append(checks, check)
+	}
+
+	if len(checks) == 0 && assertion.Level != nil {
+		checks = append(checks, AssertionCheck{
+			Assertion: assertion.Text,
+			Benchmark: benchmark,
+			Level:     assertion.Level.String(),
+			Message:   fmt.Sprintf("no result at %v", *assertion.Level),
+		})
+	}
+	return checks
+}
+
+func (assertion Assertion) holds(value float64) bool {
+	switch assertion.Op {
+	case ">=":
+		return value >= assertion.Value
+	case "<=":
+		return value <= assertion.Value
+	case ">":
+		return value > assertion.Value
+	case "<":
+		return value < assertion.Value
+	default:
+		return value == assertion.Value
+	}
+}
+
+// printAssertionChecks prints every check and returns the number that failed.
+func printAssertionChecks(w io.Writer, checks []AssertionCheck) int {
+	bold := "\033[1m"
+	green := "\033[32m"
+	red := "\033[31m"
+	reset := "\033[0m"
+
+	failed := 0
+	fmt.Fprintf(w, "\n%s%sAssertions%s\n", green, bold, reset)
+	for _, check := range checks {
+		color, status := green, "PASS"
+		if !check.Passed {
+			color, status = red, "FAIL"
+			failed++
+		}
+		name := check.Level
+		if check.Benchmark != "" {
+			name = check.Benchmark + ", " + name
+		}
+		fmt.Fprintf(w, "%s%s  %s (%s): %s%s\n", color, status, check.Assertion, name, check.Message, reset)
+	}
+	fmt.Fprintf(w, "%s%d of %d assertions passed%s\n", green, len(checks)-failed, len(checks), reset)
+	return failed
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes checks as a JUnit XML report with one test suite per
+// benchmark and one test case per assertion and level.
+func writeJUnit(path string, checks []AssertionCheck) error {
+	report := junitTestSuites{}
+	suites := map[string]int{}
+	for _, check := range checks {
+		name := check.Benchmark
+		if name == "" {
+			name = "llmapibenchmark"
+		}
+		index, ok := suites[name]
+		if !ok {
+			index = len(report.Suites)
+			suites[name] = index
+			report.Suites = append(report.Suites, junitTestSuite{Name: name})
+		}
+
+		testCase := junitTestCase{Name: check.Level + ": " + check.Assertion, Classname: name}
+		if !check.Passed {
+			testCase.Failure = &junitFailure{Message: check.Message, Text: check.Message}
+			report.Suites[index].Failures++
+			report.Failures++
+		}
+		report.Suites[index].Cases = append(report.Suites[index].Cases, testCase)
+		report.Suites[index].Tests++
+		report.Tests++
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling JUnit report: %v", err)
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0644)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
+)
+
+func TestParseAssertion(t *testing.T) {
+	for _, test := range []struct {
+		text       string
+		level      *Level
+		metric     string
+		percentile float64
+		op         string
+		value      float64
+	}{
+		{"gen_tps>=400", nil, "gen_tps", 0, ">=", 400},
+		{"conc=16:gen_tps >= 400", &Level{Concurrency: 16}, "gen_tps", 0, ">=", 400},
+		{"rate=2.5:req_per_sec>2", &Level{Rate: 2.5}, "req_per_sec", 0, ">", 2},
+		{"p95_ttft<1.5s", nil, "p95_ttft", 95, "<", 1.5},
+		{"p99.9_e2e<=2500ms", nil, "p99.9_e2e", 99.9, "<=", 2.5},
+		{"tpot<50ms", nil, "tpot", 0, "<", 50},
+		{"mean_tpot<0.05s", nil, "mean_tpot", 0, "<", 50},
+		{"max_stall<1s", nil, "max_stall", 0, "<", 1000},
+		{"success_rate>=99%", nil, "success_rate", 0, ">=", 0.99},
+		{"success_rate>=0.99", nil, "success_rate", 0, ">=", 0.99},
+		{"success_rate==1", nil, "success_rate", 0, "==", 1},
+	} {
+		assertion, err := ParseAssertion(test.text)
+		if err != nil {
+			t.Errorf("ParseAssertion(%q): %v", test.text, err)
+			continue
+		}
+		if (assertion.Level == nil) != (test.level == nil) || (test.level != nil && *assertion.Level != *test.level) {
+			t.Errorf("ParseAssertion(%q) level = %v, want %v", test.text, assertion.Level, test.level)
+		}
+		if assertion.Metric != test.metric || assertion.Percentile != test.percentile || assertion.Op != test.op || assertion.Value != test.value {
+			t.Errorf("ParseAssertion(%q) = %s p%g %s %g, want %s p%g %s %g", test.text,
+				assertion.Metric, assertion.Percentile, assertion.Op, assertion.Value, test.metric, test.percentile, test.op, test.value)
+		}
+	}
+}
+
+func TestParseAssertionErrors(t *testing.T) {
+	for _, text := range []string{
+		"gen_tps",
+		"gen_tps=>400",
+		"conc=0:gen_tps>=400",
+		"conc=1.5:gen_tps>=400",
+		"conc=-2:gen_tps>=400",
+		"rate=0:gen_tps>=400",
+		"tokens>=400",
+		"p101_ttft<1s",
+		"p0_ttft<1s",
+		"max_ttft_ttft<1s",
+		"p95_ttft<soon",
+		"gen_tps>=400%",
+		"success_rate>=99",
+		"slo_attainment>1.5",
+	} {
+		if _, err := ParseAssertion(text); err == nil {
+			t.Errorf("ParseAssertion(%q) accepted", text)
+		}
+	}
+}
+
+func TestAssertionCheck(t *testing.T) {
+	measured := utils.SpeedResult{
+		Concurrency:     4,
+		GenerationSpeed: 500,
+		SuccessRate:     1,
+		MaxTtft:         0.8,
+		Ttft:            utils.NewDistribution([]float64{0.2, 0.4, 0.8}, []float64{95}),
+		Tpot:            utils.NewDistribution([]float64{20, 30}, []float64{95}),
+	}
+	// Every request failed, so there are no latencies
+	failed := utils.SpeedResult{Concurrency: 8}
+
+	for _, test := range []struct {
+		text string
+		want []bool
+	}{
+		{"gen_tps>=400", []bool{true, false}},
+		{"conc=4:gen_tps>=400", []bool{true}},
+		{"conc=16:gen_tps>=400", []bool{false}},
+		{"ttft<1.5s", []bool{true, false}},
+		{"p95_ttft<0.5s", []bool{false, false}},
+		{"mean_tpot<=25ms", []bool{true, false}},
+		{"max_ttft<2s", []bool{true, false}},
+		{"min_ttft<2s", []bool{true, false}},
+		{"max_stall<1s", []bool{true, false}},
+		{"goodput_rps>0", []bool{false, false}},
+		{"success_rate>=99%", []bool{true, false}},
+	} {
+		assertion, err := ParseAssertion(test.text)
+		if err != nil {
+			t.Fatalf("ParseAssertion(%q): %v", test.text, err)
+		}
+		checks := assertion.Check("model", []utils.SpeedResult{measured, failed})
+		var got []bool
+		for _, check := range checks {
+			got = append(got, check.Passed)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s: %d checks, want %d", test.text, len(got), len(test.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%s: check %d (%s) passed = %v, want %v", test.text, i, checks[i].Message, got[i], test.want[i])
+			}
+		}
+	}
+}
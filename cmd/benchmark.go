@@ -3,17 +3,22 @@ package main
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
+	"github.com/Yoosu-L/llmapibenchmark/internal/workload"
 	"github.com/schollz/progressbar/v3"
 )
 
-func (benchmark *Benchmark) runCli() error {
+// trialConfidence is the confidence level of the intervals reported for repeated trials.
+const trialConfidence = 0.95
+
+func (benchmark *Benchmark) runCli() (BenchmarkResult, error) {
 	// Test latency
 	latency, err := utils.MeasureLatency(benchmark.BaseURL, 5)
 	if err != nil {
-		return fmt.Errorf("latency test error: %v", err)
+		return BenchmarkResult{}, fmt.Errorf("latency test error: %v", err)
 	}
 
 	// Print benchmark header
@@ -23,87 +28,259 @@ func (benchmark *Benchmark) runCli() error {
 	bold := "\033[1m"
 	green := "\033[32m"
 	reset := "\033[0m"
-	fmt.Printf("%s%s| Conc | Gen TPS | Prompt TPS | Min TTFT(s) | Max TTFT(s) | Success | Total(s) |%s\n", green, bold, reset)
-	fmt.Printf("%s|:----:|:-------:|:----------:|:-----------:|:-----------:|:-------:|:--------:|%s\n", green, reset)
+	openLoop := len(benchmark.Rates) > 0
+	header, separator := utils.ResultsTableHeader(openLoop)
+	fmt.Printf("%s%s%s%s\n", green, bold, header, reset)
+	fmt.Printf("%s%s%s\n", green, separator, reset)
 
-	// Test each concurrency level and print results
-	var results [][]interface{}
-	for _, concurrency := range benchmark.ConcurrencyLevels {
-		result, err := benchmark.measureSpeed(latency, concurrency, true)
-		if err != nil {
-			return fmt.Errorf("concurrency %d: %v", concurrency, err)
+	// Test each level and print results as they come in
+	results, search, err := benchmark.sweep(latency, true, func(result utils.SpeedResult) {
+		fmt.Printf("%s%s%s\n", green, utils.ResultsTableRow(result, openLoop), reset)
+	})
+	if err != nil {
+		return BenchmarkResult{}, err
+	}
+
+	fmt.Printf("%s%s%s\n", green, separator, reset)
+
+	// Print per-request latency and decode timing distributions
+	tables := benchmark.levelTables(results, openLoop)
+	for _, table := range tables {
+		header, separator := table.Header()
+		fmt.Printf("\n%s%s%s%s\n", green, bold, header, reset)
+		fmt.Printf("%s%s%s\n", green, separator, reset)
+		for _, result := range results {
+			fmt.Printf("%s%s%s\n", green, table.Row(result), reset)
 		}
+		fmt.Printf("%s%s%s\n", green, separator, reset)
+	}
 
-		// Print current results
-		fmt.Printf("%s| %4d | %7.2f | %10.2f | %11.2f | %11.2f | %6.2f%% | %8.2f |%s\n",
-			green,
-			concurrency,
-			result.GenerationSpeed,
-			result.PromptThroughput,
-			result.MinTtft,
-			result.MaxTtft,
-			result.SuccessRate*100,
-			result.Duration,
-			reset,
-		)
-
-		// Save results for later
-		results = append(results, []interface{}{
-			concurrency,
-			result.GenerationSpeed,
-			result.PromptThroughput,
-			result.MinTtft,
-			result.MaxTtft,
-			result.SuccessRate,
-			result.Duration,
-		})
+	if benchmark.Charts {
+		printCharts(results, openLoop)
 	}
 
-	fmt.Printf("%s|:----:|:-------:|:----------:|:-----------:|:-----------:|:-------:|:--------:|%s\n", green, reset)
+	if benchmark.MinUserSpeed > 0 {
+		if maxReadable, ok := maxReadableConcurrency(results, benchmark.MinUserSpeed); ok {
+			fmt.Printf("\n%sMedian per-user decode speed stays at or above %.2f tokens/s up to concurrency %d%s\n", green, benchmark.MinUserSpeed, maxReadable, reset)
+		} else {
+			fmt.Printf("\n%sMedian per-user decode speed is below %.2f tokens/s at every level%s\n", green, benchmark.MinUserSpeed, reset)
+		}
+	}
+	if search != nil {
+		fmt.Printf("\n%s%sCapacity:%s%s %d concurrent requests meet the SLOs (p%d, success rate >= %.2f%%)",
+			green, bold, reset, green, search.Capacity, searchPercentile, search.MinSuccessRate*100)
+		if search.Capacity == search.MaxConcurrency {
+			fmt.Printf(", the --search-max limit")
+		}
+		fmt.Printf("%s\n", reset)
+	}
 	fmt.Println("\n" + "\033[36m" + strings.Repeat("=", 80) + "\033[0m")
 
 	// Save results to Markdown
-	utils.SaveResultsToMD(results, benchmark.ModelName, benchmark.InputTokens, benchmark.MaxTokens, latency)
+	utils.SaveResultsToMD(results, openLoop, tables, benchmark.ModelName, benchmark.ReportLabel, benchmark.InputTokens, benchmark.MaxTokens, latency)
 
-	return nil
+	return benchmark.result(latency, results, search), nil
+}
+
+// printCharts draws Gen TPS and median TTFT against load, and the per-request
+// TTFT and latency distribution of every level.
+func printCharts(results []utils.SpeedResult, openLoop bool) {
+	green := "\033[32m"
+	reset := "\033[0m"
+
+	var labels []string
+	var genTps, ttft []float64
+	var ttfts, latencies [][]float64
+	for _, result := range results {
+		label := fmt.Sprintf("conc %d", result.Concurrency)
+		if openLoop {
+			label = fmt.Sprintf("rate %g", result.Rate)
+		}
+		labels = append(labels, label)
+		genTps = append(genTps, result.GenerationSpeed)
+		ttft = append(ttft, result.Ttft.Median)
+
+		var levelTtfts, levelLatencies []float64
+		for _, request := range result.Requests {
+			if request.Error == "" {
+				levelTtfts = append(levelTtfts, request.Ttft())
+				levelLatencies = append(levelLatencies, request.Latency())
+			}
+		}
+		ttfts = append(ttfts, levelTtfts)
+		latencies = append(latencies, levelLatencies)
+	}
+
+	fmt.Printf("\n%s%s%s", green, utils.BarChart("Gen TPS", labels, genTps, " tok/s"), reset)
+	fmt.Printf("\n%s%s%s", green, utils.BarChart("TTFT P50", labels, ttft, "s"), reset)
+	fmt.Printf("\n%s%s%s", green, utils.HistogramChart("Per-request TTFT", labels, ttfts, "s"), reset)
+	fmt.Printf("\n%s%s%s", green, utils.HistogramChart("Per-request end-to-end latency", labels, latencies, "s"), reset)
 }
 
 func (benchmark *Benchmark) run() (BenchmarkResult, error) {
+	// Test latency
+	latency, err := utils.MeasureLatency(benchmark.BaseURL, 5)
+	if err != nil {
+		return BenchmarkResult{}, fmt.Errorf("error testing latency: %v", err)
+	}
+
+	results, search, err := benchmark.sweep(latency, false, func(utils.SpeedResult) {})
+	if err != nil {
+		return BenchmarkResult{}, err
+	}
+
+	return benchmark.result(latency, results, search), nil
+}
+
+// result collects the measured levels together with the settings they were
+// measured under.
+func (benchmark *Benchmark) result(latency float64, results []utils.SpeedResult, search *SearchResult) BenchmarkResult {
 	result := BenchmarkResult{}
+	result.Provider = benchmark.Provider
+	result.BaseURL = benchmark.BaseURL
 	result.ModelName = benchmark.ModelName
 	result.InputTokens = benchmark.InputTokens
 	result.MaxTokens = benchmark.MaxTokens
-
-	// Test latency
-	latency, err := utils.MeasureLatency(benchmark.BaseURL, 5)
-	if err != nil {
-		return result, fmt.Errorf("error testing latency: %v", err)
+	if len(benchmark.Rates) > 0 {
+		result.Arrival = benchmark.Arrival
+		result.Seed = benchmark.Seed
+	}
+	if len(benchmark.Dataset) > 0 {
+		result.Dataset = benchmark.DatasetPath
+		result.Seed = benchmark.Seed
+	}
+	if benchmark.InputLength != nil || benchmark.OutputLength != nil {
+		result.InputDist = benchmark.InputDist
+		result.OutputDist = benchmark.OutputDist
+		result.Seed = benchmark.Seed
+	}
+	if benchmark.Slo.Enabled() {
+		result.Slo = newSloSpec(benchmark.Slo)
+	}
+	if benchmark.repeated() {
+		result.Repeat = benchmark.Repeat
+		result.RepeatCi = benchmark.RepeatCi
+	}
+	if benchmark.LevelDuration > 0 {
+		result.LevelDuration = benchmark.LevelDuration.Seconds()
+		result.Warmup = benchmark.Warmup.Seconds()
 	}
 	result.Latency = latency
+	result.Results = results
+	result.Search = search
 
+	if benchmark.MinUserSpeed > 0 {
+		result.MinUserSpeed = benchmark.MinUserSpeed
+		result.MaxReadableConcurrency, _ = maxReadableConcurrency(results, benchmark.MinUserSpeed)
+	}
+
+	return result
+}
+
+// levelTables returns the tables reported after the results table: the goodput
+// table when SLOs are set, the realised lengths when they vary per request and
+// the server's own timings when it reported them.
+func (benchmark *Benchmark) levelTables(results []utils.SpeedResult, openLoop bool) []utils.LevelTable {
+	tables := utils.LevelTables(benchmark.Percentiles, openLoop)
+	if benchmark.Slo.Enabled() {
+		tables = append(tables, utils.GoodputTable(openLoop))
+	}
+	if len(benchmark.Dataset) > 0 || benchmark.InputLength != nil || benchmark.OutputLength != nil {
+		tables = append(tables, utils.LengthTable(benchmark.Percentiles, openLoop))
+	}
+	if slices.ContainsFunc(results, func(result utils.SpeedResult) bool { return result.Server != nil }) {
+		cached := slices.ContainsFunc(results, func(result utils.SpeedResult) bool {
+			return result.Server != nil && result.Server.CachedTokens != nil
+		})
+		tables = append(tables, utils.ServerTable(openLoop, cached))
+	}
+	if benchmark.repeated() {
+		tables = append(tables, utils.TrialsTable(openLoop))
+	}
+	return tables
+}
+
+// maxReadableConcurrency returns the highest concurrency whose median per-user
+// decode speed is at least minUserSpeed, and false if no level meets it.
+func maxReadableConcurrency(results []utils.SpeedResult, minUserSpeed float64) (int, bool) {
+	maxReadable, found := 0, false
+	for _, result := range results {
+		if result.UserSpeed.Median >= minUserSpeed && result.Concurrency > maxReadable {
+			maxReadable, found = result.Concurrency, true
+		}
+	}
+	return maxReadable, found
+}
+
+// levels returns the load levels to sweep: request rates for open-loop runs,
+// concurrency levels otherwise.
+func (benchmark *Benchmark) levels() []Level {
+	var levels []Level
+	if len(benchmark.Rates) > 0 {
+		for _, rate := range benchmark.Rates {
+			levels = append(levels, Level{Rate: rate})
+		}
+		return levels
+	}
 	for _, concurrency := range benchmark.ConcurrencyLevels {
-		measurement, err := benchmark.measureSpeed(latency, concurrency, false)
+		levels = append(levels, Level{Concurrency: concurrency})
+	}
+	return levels
+}
+
+// repeated reports whether each level is measured in several trials.
+func (benchmark *Benchmark) repeated() bool {
+	return benchmark.Repeat > 1 || benchmark.RepeatCi > 0
+}
+
+// measureTrials measures level Repeat times, and with RepeatCi keeps going until
+// the confidence interval of the generation throughput is within RepeatCi
+// percent of its mean or RepeatMax trials were run. The trials are averaged.
+func (benchmark *Benchmark) measureTrials(latency float64, level Level, clearProgress bool) (utils.SpeedResult, error) {
+	if !benchmark.repeated() {
+		return benchmark.measureSpeed(latency, level, clearProgress)
+	}
+
+	var trials []utils.SpeedResult
+	for {
+		result, err := benchmark.measureSpeed(latency, level, clearProgress)
 		if err != nil {
-			return result, fmt.Errorf("concurrency %d: %v", concurrency, err)
+			return result, fmt.Errorf("trial %d: %v", len(trials)+1, err)
 		}
+		trials = append(trials, result)
+		aggregated := utils.AggregateTrials(trials, trialConfidence)
 
-		result.Results = append(result.Results, measurement)
+		if len(trials) < benchmark.Repeat {
+			continue
+		}
+		if benchmark.RepeatCi == 0 || len(trials) >= benchmark.RepeatMax {
+			return aggregated, nil
+		}
+		estimate := aggregated.Trials.Fields["generation_speed"]
+		if len(trials) >= 2 && estimate.HalfWidth() <= estimate.Mean*benchmark.RepeatCi/100 {
+			return aggregated, nil
+		}
 	}
-
-	return result, nil
 }
 
-func (benchmark *Benchmark) measureSpeed(latency float64, concurrency int, clearProgress bool) (utils.SpeedResult, error) {
+func (benchmark *Benchmark) measureSpeed(latency float64, level Level, clearProgress bool) (utils.SpeedResult, error) {
 
 	// Disable terminal auto-wrap (DECAWM) to prevent the progress bar from breaking into multiple new lines
 	fmt.Fprint(os.Stderr, "\x1b[?7l")
 	// Re-enable terminal auto-wrap when the function returns
 	defer fmt.Fprint(os.Stderr, "\x1b[?7h")
 
-	// Create a progress bar for this specific concurrency level
-	expectedTokens := concurrency * benchmark.MaxTokens
+	// Create a progress bar for this specific level
+	expectedTokens := level.Concurrency * benchmark.MaxTokens
 	// Pad description to a fixed length for consistent alignment
-	description := fmt.Sprintf("Conc %-2d", concurrency)
+	description := fmt.Sprintf("Conc %-2d", level.Concurrency)
+	if level.Rate > 0 {
+		expectedTokens = int(level.Rate*(benchmark.Warmup+benchmark.LevelDuration).Seconds()) * benchmark.MaxTokens
+		description = fmt.Sprintf("Rate %-4g", level.Rate)
+	} else if benchmark.LevelDuration > 0 {
+		// Steady-state levels refill until the deadline, so the total is unknown
+		expectedTokens = -1
+	}
 	barWidth := 20
 
 	bar := progressbar.NewOptions(expectedTokens,
@@ -119,19 +296,34 @@ func (benchmark *Benchmark) measureSpeed(latency float64, concurrency int, clear
 	)
 
 	speedMeasurement := utils.SpeedMeasurement{
-		BaseUrl:     benchmark.BaseURL,
-		ApiVersion:  benchmark.ApiVersion,
-		ApiKey:      benchmark.ApiKey,
+		Client:      benchmark.Client,
 		ModelName:   benchmark.ModelName,
 		Prompt:      benchmark.Prompt,
 		NumWords:    benchmark.NumWords,
 		MaxTokens:   benchmark.MaxTokens,
 		Latency:     latency,
-		Concurrency: concurrency,
+		Concurrency: level.Concurrency,
+		Rate:        level.Rate,
+		Arrival:     benchmark.Arrival,
+		Duration:    benchmark.LevelDuration,
+		Warmup:      benchmark.Warmup,
+		Seed:        benchmark.Seed,
+		Percentiles: benchmark.Percentiles,
+		Slo:         benchmark.Slo,
 	}
 	if benchmark.UseRandomInput {
 		speedMeasurement.UseRandomInput = true
 	}
+	if len(benchmark.Dataset) > 0 {
+		// Every level draws the same sequence of prompts
+		speedMeasurement.Workload = workload.NewDataset(benchmark.Dataset, benchmark.Seed, benchmark.DatasetMaxOutput)
+	} else if benchmark.InputLength != nil || benchmark.OutputLength != nil || (benchmark.UseRandomInput && benchmark.SeedPrompts) {
+		var input workload.Length = benchmark.InputLength
+		if input == nil && benchmark.UseRandomInput {
+			input = workload.FixedLength(benchmark.NumWords * 4)
+		}
+		speedMeasurement.Workload = workload.NewSynthetic(input, benchmark.OutputLength, benchmark.Prompt, benchmark.Seed)
+	}
 
 	result, err := speedMeasurement.Run(bar)
 	if err != nil {
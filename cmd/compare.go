@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/api"
+	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
+	"github.com/spf13/pflag"
+	"go.yaml.in/yaml/v4"
+)
+
+// compareMetric is a metric checked by the compare subcommand. Regressions are
+// judged in percent of the baseline, or in percentage points for rates.
+type compareMetric struct {
+	title        string
+	higherBetter bool
+	points       bool
+	tolerance    float64
+	value        func(utils.SpeedResult) float64
+}
+
+// runCompare compares saved benchmark results against a baseline, level by
+// level, and exits with status 1 if any metric regressed beyond its tolerance.
+func runCompare(args []string) {
+	flags := pflag.NewFlagSet("compare", pflag.ExitOnError)
+	genTolerance := flags.Float64("gen-tps-tolerance", 5, "Allowed drop in generation throughput, in percent of the baseline")
+	promptTolerance := flags.Float64("prompt-tps-tolerance", 10, "Allowed drop in prompt throughput, in percent of the baseline")
+	ttftTolerance := flags.Float64("ttft-tolerance", 10, "Allowed increase in median and max TTFT, in percent of the baseline")
+	successTolerance := flags.Float64("success-tolerance", 0, "Allowed drop in success rate, in percentage points")
+	help := flags.BoolP("help", "h", false, "Show this help message")
+	flags.Parse(args)
+
+	if *help || flags.NArg() < 2 {
+		fmt.Printf("Usage: %s compare [flags] BASELINE CANDIDATE...\n", os.Args[0])
+		flags.PrintDefaults()
+		if *help {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
+
+	metrics := compareMetrics(*genTolerance, *promptTolerance, *ttftTolerance, *successTolerance)
+
+	baseline, err := loadResults(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	regressions := 0
+	for _, path := range flags.Args()[1:] {
+		candidate, err := loadResults(path)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		regressions += compareFiles(flags.Arg(0), baseline, path, candidate, metrics)
+	}
+
+	if regressions > 0 {
+		fmt.Printf("\033[31m\033[1m%d regression(s) beyond tolerance\033[0m\n", regressions)
+		os.Exit(1)
+	}
+	fmt.Printf("\033[32m\033[1mNo regressions beyond tolerance\033[0m\n")
+}
+
+// loadResults reads the JSON or YAML output of a benchmark run, either a single
+// result or a report combining several.
+func loadResults(path string) ([]BenchmarkResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading results: %v", err)
+	}
+
+	// JSON and YAML use different field names, so pick the decoder by content
+	unmarshal := yaml.Unmarshal
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		unmarshal = json.Unmarshal
+	}
+
+	var report Report
+	if err := unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	if len(report.Benchmarks) > 0 {
+		return report.Benchmarks, nil
+	}
+	var result BenchmarkResult
+	if err := unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	if len(result.Results) == 0 {
+		return nil, fmt.Errorf("%s holds no benchmark results", path)
+	}
+	return []BenchmarkResult{result}, nil
+}
+
+// compareMetrics returns the metrics checked by the compare subcommand with
+// their tolerances.
+func compareMetrics(genTolerance, promptTolerance, ttftTolerance, successTolerance float64) []compareMetric {
+	return []compareMetric{
+		{"Gen TPS", true, false, genTolerance, func(result utils.SpeedResult) float64 { return result.GenerationSpeed }},
+		{"Prompt TPS", true, false, promptTolerance, func(result utils.SpeedResult) float64 { return result.PromptThroughput }},
+		{"TTFT P50(s)", false, false, ttftTolerance, func(result utils.SpeedResult) float64 { return result.Ttft.Median }},
+		{"Max TTFT(s)", false, false, ttftTolerance, func(result utils.SpeedResult) float64 { return result.MaxTtft }},
+		{"Success", true, true, successTolerance, func(result utils.SpeedResult) float64 { return result.SuccessRate * 100 }},
+	}
+}
+
+// compareFiles prints the comparison of every benchmark in candidate with the
+// matching one in baseline and returns the number of regressions. Benchmarks
+// are matched by scenario, provider, endpoint and model unless each file holds
+// a single one.
+func compareFiles(baselinePath string, baseline []BenchmarkResult, candidatePath string, candidate []BenchmarkResult, metrics []compareMetric) int {
+	regressions := 0
+	for _, candidateResult := range candidate {
+		var baselineResult *BenchmarkResult
+		if len(baseline) == 1 && len(candidate) == 1 {
+			baselineResult = &baseline[0]
+		} else {
+			for i := range baseline {
+				if sameTarget(baseline[i], candidateResult) {
+					baselineResult = &baseline[i]
+					break
+				}
+			}
+		}
+
+		title := fmt.Sprintf("%s: %s at %s", candidatePath, candidateResult.ModelName, candidateResult.BaseURL)
+		if candidateResult.Scenario != "" {
+			title += " (" + candidateResult.Scenario + ")"
+		}
+		fmt.Printf("\033[36m\033[1m%s vs %s\033[0m\n", title, baselinePath)
+		if baselineResult == nil {
+			fmt.Printf("\033[33mNo matching benchmark in the baseline, skipped\033[0m\n\n")
+			continue
+		}
+		regressions += compareLevels(baselineResult.Results, candidateResult.Results, metrics)
+	}
+	return regressions
+}
+
+// sameTarget reports whether two results benchmarked the same scenario, model
+// and endpoint. Results saved before providers existed were all OpenAI.
+func sameTarget(a BenchmarkResult, b BenchmarkResult) bool {
+	provider := func(result BenchmarkResult) string {
+		if result.Provider == "" {
+			return api.ProviderOpenAI
+		}
+		return result.Provider
+	}
+	return a.Scenario == b.Scenario && a.ModelName == b.ModelName && a.BaseURL == b.BaseURL && provider(a) == provider(b)
+}
+
+// compareLevels prints the deltas of every level present in both results and
+// returns the number of regressions. Baseline levels missing from the candidate
+// count as regressions.
+func compareLevels(baseline []utils.SpeedResult, candidate []utils.SpeedResult, metrics []compareMetric) int {
+	green := "\033[32m"
+	red := "\033[31m"
+	bold := "\033[1m"
+	reset := "\033[0m"
+
+	header := "|    Load    |    Metric    |  Baseline  | Candidate  |  Delta %  |   Result   |"
+	separator := "|:----------:|:-------------|:----------:|:----------:|:---------:|:----------:|"
+	fmt.Printf("%s%s%s%s\n", green, bold, header, reset)
+	fmt.Printf("%s%s%s\n", green, separator, reset)
+
+	regressions := 0
+	for _, candidateLevel := range candidate {
+		load := compareLoad(candidateLevel)
+		var baselineLevel *utils.SpeedResult
+		for i := range baseline {
+			if compareLoad(baseline[i]) == load {
+				baselineLevel = &baseline[i]
+				break
+			}
+		}
+		if baselineLevel == nil {
+			fmt.Printf("%s| %10s | %-12s | %10s | %10s | %9s | %10s |%s\n", green, load, "-", "-", "-", "-", "new level", reset)
+			continue
+		}
+
+		for _, metric := range metrics {
+			before, after := metric.value(*baselineLevel), metric.value(candidateLevel)
+			if before == 0 && after == 0 {
+				// Not recorded, e.g. by older versions of the tool
+				continue
+			}
+
+			change := after - before
+			worse := change
+			if metric.higherBetter {
+				worse = -change
+			}
+			delta := fmt.Sprintf("%+.2fpp", change)
+			regressed := worse > metric.tolerance
+			if !metric.points {
+				if before == 0 {
+					// No percentage of zero, so any change for the worse counts
+					delta, regressed = "-", worse > 0
+				} else {
+					delta = fmt.Sprintf("%+.2f%%", change/before*100)
+					regressed = worse/before*100 > metric.tolerance
+				}
+			}
+
+			color, status := green, "ok"
+			if regressed {
+				color, status = red, "REGRESSION"
+				regressions++
+			}
+			fmt.Printf("%s| %10s | %-12s | %10.2f | %10.2f | %9s | %10s |%s\n", color, load, metric.title, before, after, delta, status, reset)
+		}
+	}
+
+	for _, baselineLevel := range baseline {
+		load := compareLoad(baselineLevel)
+		found := false
+		for _, candidateLevel := range candidate {
+			if compareLoad(candidateLevel) == load {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fmt.Printf("%s| %10s | %-12s | %10s | %10s | %9s | %10s |%s\n", red, load, "-", "-", "-", "-", "MISSING", reset)
+			regressions++
+		}
+	}
+	fmt.Printf("%s%s%s\n\n", green, separator, reset)
+	return regressions
+}
+
+// compareLoad identifies a level: its request rate in open-loop runs, its
+// concurrency otherwise.
+func compareLoad(result utils.SpeedResult) string {
+	if result.Rate > 0 {
+		return fmt.Sprintf("rate %g", result.Rate)
+	}
+	return fmt.Sprintf("conc %d", result.Concurrency)
+}
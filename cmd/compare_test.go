@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
+)
+
+// compareLevel returns a closed level with the metrics compare checks.
+func compareLevel(concurrency int, genTps float64, ttft float64, successRate float64) utils.SpeedResult {
+	return utils.SpeedResult{
+		Concurrency:      concurrency,
+		GenerationSpeed:  genTps,
+		PromptThroughput: 100,
+		MaxTtft:          ttft,
+		Ttft:             utils.Distribution{Median: ttft},
+		SuccessRate:      successRate,
+	}
+}
+
+func TestCompareLevels(t *testing.T) {
+	metrics := compareMetrics(5, 10, 10, 0)
+	baseline := []utils.SpeedResult{compareLevel(1, 100, 0.5, 1), compareLevel(8, 500, 1, 1)}
+	for _, test := range []struct {
+		name      string
+		candidate []utils.SpeedResult
+		want      int
+	}{
+		{"unchanged", baseline, 0},
+		{"within tolerance", []utils.SpeedResult{compareLevel(1, 96, 0.54, 1), compareLevel(8, 480, 1.09, 1)}, 0},
+		{"throughput drop", []utils.SpeedResult{compareLevel(1, 100, 0.5, 1), compareLevel(8, 300, 1, 1)}, 1},
+		{"slower TTFT counts median and max", []utils.SpeedResult{compareLevel(1, 100, 0.6, 1), compareLevel(8, 500, 1, 1)}, 2},
+		{"failed requests", []utils.SpeedResult{compareLevel(1, 100, 0.5, 0.99), compareLevel(8, 500, 1, 1)}, 1},
+		{"improvement", []utils.SpeedResult{compareLevel(1, 200, 0.1, 1), compareLevel(8, 900, 0.2, 1)}, 0},
+		{"missing level", []utils.SpeedResult{compareLevel(1, 100, 0.5, 1)}, 1},
+		{"new level", append([]utils.SpeedResult{compareLevel(32, 10, 9, 0)}, baseline...), 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := compareLevels(baseline, test.candidate, metrics); got != test.want {
+				t.Errorf("regressions = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompareLevelsZeroBaseline(t *testing.T) {
+	metrics := compareMetrics(5, 10, 10, 0)
+	baseline := []utils.SpeedResult{compareLevel(1, 0, 0, 0)}
+	// From nothing to anything is an improvement for throughput, but any TTFT counts as worse
+	if got := compareLevels(baseline, []utils.SpeedResult{compareLevel(1, 100, 0, 1)}, metrics); got != 0 {
+		t.Errorf("regressions with more throughput = %d, want 0", got)
+	}
+	if got := compareLevels(baseline, []utils.SpeedResult{compareLevel(1, 0, 0.01, 0)}, metrics); got != 2 {
+		t.Errorf("regressions with higher TTFT = %d, want 2", got)
+	}
+}
+
+func TestCompareFilesMatchesEndpoints(t *testing.T) {
+	metrics := compareMetrics(5, 10, 10, 0)
+	target := func(baseURL string, genTps float64) BenchmarkResult {
+		return BenchmarkResult{Provider: "openai", BaseURL: baseURL, ModelName: "m", Results: []utils.SpeedResult{compareLevel(1, genTps, 1, 1)}}
+	}
+	baseline := []BenchmarkResult{target("http://a", 100), target("http://b", 500)}
+	candidate := []BenchmarkResult{target("http://a", 100), target("http://b", 300)}
+	if got := compareFiles("baseline.json", baseline, "candidate.json", candidate, metrics); got != 1 {
+		t.Errorf("regressions = %d, want 1", got)
+	}
+
+	// Results saved before providers existed were all OpenAI
+	baseline[1].Provider = ""
+	candidate[1].Provider = "anthropic"
+	if got := compareFiles("baseline.json", baseline, "candidate.json", candidate, metrics); got != 0 {
+		t.Errorf("regressions across providers = %d, want 0", got)
+	}
+}
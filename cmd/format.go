@@ -1,9 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
 	"go.yaml.in/yaml/v4"
 )
 
@@ -24,3 +31,195 @@ func (benchmark *BenchmarkResult) Yaml() (string, error) {
 
 	return string(yamlData), nil
 }
+
+func (report *Report) Json() (string, error) {
+	prettyJSON, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return "", fmt.Errorf("error marshalling JSON: %w", err)
+	}
+
+	return string(prettyJSON), nil
+}
+
+func (report *Report) Yaml() (string, error) {
+	yamlData, err := yaml.Marshal(&report)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling yaml: %v", err)
+	}
+
+	return string(yamlData), nil
+}
+
+// csvDistributions are the per-request distributions written to CSV, with their column prefix.
+var csvDistributions = []struct {
+	name  string
+	value func(utils.SpeedResult) utils.Distribution
+}{
+	{"ttft", func(result utils.SpeedResult) utils.Distribution { return result.Ttft }},
+	{"e2e_latency", func(result utils.SpeedResult) utils.Distribution { return result.E2eLatency }},
+	{"tpot_ms", func(result utils.SpeedResult) utils.Distribution { return result.Tpot }},
+	{"itl_ms", func(result utils.SpeedResult) utils.Distribution { return result.Itl }},
+	{"user_speed", func(result utils.SpeedResult) utils.Distribution { return result.UserSpeed }},
+	{"input_length", func(result utils.SpeedResult) utils.Distribution { return result.InputLength }},
+	{"output_length", func(result utils.SpeedResult) utils.Distribution { return result.OutputLength }},
+}
+
+// Csv formats the results with one row per benchmark and level.
+func Csv(results []BenchmarkResult) (string, error) {
+	// Every row gets a column for each percentile reported by any level
+	keys := map[string]float64{}
+	for _, result := range results {
+		for _, level := range result.Results {
+			for key := range level.Ttft.Percentiles {
+				p, _ := strconv.ParseFloat(strings.TrimPrefix(key, "p"), 64)
+				keys[key] = p
+			}
+		}
+	}
+	percentiles := make([]string, 0, len(keys))
+	for key := range keys {
+		percentiles = append(percentiles, key)
+	}
+	sort.Slice(percentiles, func(i, j int) bool { return keys[percentiles[i]] < keys[percentiles[j]] })
+
+	header := []string{"scenario", "provider", "base_url", "model_name", "input_tokens", "output_tokens", "latency_ms",
+		"concurrency", "rate", "request_throughput", "generation_speed", "prompt_throughput",
+		"min_ttft", "max_ttft", "success_rate", "duration", "max_stall_ms"}
+	for _, distribution := range csvDistributions {
+		header = append(header, distribution.name+"_mean", distribution.name+"_median")
+		for _, key := range percentiles {
+			header = append(header, distribution.name+"_"+key)
+		}
+	}
+	header = append(header, "goodput_request_rate", "goodput_token_rate", "goodput_attainment", "trials")
+
+	rows := [][]string{header}
+	for _, result := range results {
+		for _, level := range result.Results {
+			row := []string{result.Scenario, result.Provider, result.BaseURL, result.ModelName,
+				strconv.Itoa(result.InputTokens), strconv.Itoa(result.MaxTokens), csvFloat(result.Latency),
+				strconv.Itoa(level.Concurrency), csvFloat(level.Rate), csvFloat(level.RequestThroughput),
+				csvFloat(level.GenerationSpeed), csvFloat(level.PromptThroughput),
+				csvFloat(level.MinTtft), csvFloat(level.MaxTtft), csvFloat(level.SuccessRate),
+				csvFloat(level.Duration), csvFloat(level.MaxStall)}
+			for _, distribution := range csvDistributions {
+				values := distribution.value(level)
+				row = append(row, csvFloat(values.Mean), csvFloat(values.Median))
+				for _, key := range percentiles {
+					if value, ok := values.Percentiles[key]; ok {
+						row = append(row, csvFloat(value))
+					} else {
+						row = append(row, "")
+					}
+				}
+			}
+			if level.Goodput != nil {
+				row = append(row, csvFloat(level.Goodput.RequestRate), csvFloat(level.Goodput.TokenRate), csvFloat(level.Goodput.Attainment))
+			} else {
+				row = append(row, "", "", "")
+			}
+			trials := 1
+			if level.Trials != nil {
+				trials = level.Trials.Count
+			}
+			rows = append(rows, append(row, strconv.Itoa(trials)))
+		}
+	}
+	return writeCsv(rows)
+}
+
+// RequestsCsv formats every request of the results as one row.
+func RequestsCsv(results []BenchmarkResult) (string, error) {
+	rows := [][]string{{"scenario", "base_url", "model_name", "concurrency", "rate", "start",
+		"ttft", "latency", "prompt_tokens", "completion_tokens", "success", "error"}}
+	for _, result := range results {
+		for _, level := range result.Results {
+			for _, request := range level.Requests {
+				rows = append(rows, []string{result.Scenario, result.BaseURL, result.ModelName,
+					strconv.Itoa(request.Concurrency), csvFloat(request.Rate),
+					request.Start.UTC().Format(time.RFC3339Nano),
+					strconv.FormatFloat(request.Ttft(), 'f', 6, 64),
+					strconv.FormatFloat(request.Latency(), 'f', 6, 64),
+					strconv.Itoa(request.PromptTokens), strconv.Itoa(request.CompletionTokens),
+					strconv.FormatBool(request.Error == ""), request.Error})
+			}
+		}
+	}
+	return writeCsv(rows)
+}
+
+func csvFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func writeCsv(rows [][]string) (string, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	if err := writer.WriteAll(rows); err != nil {
+		return "", fmt.Errorf("error writing CSV: %v", err)
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+// requestLine is one line of the --requests-out log.
+type requestLine struct {
+	Scenario                  string     `json:"scenario,omitempty"`
+	BaseURL                   string     `json:"base_url"`
+	ModelName                 string     `json:"model_name"`
+	Concurrency               int        `json:"concurrency,omitempty"`
+	Rate                      float64    `json:"rate,omitempty"`
+	Start                     time.Time  `json:"start"`
+	FirstToken                *time.Time `json:"first_token,omitempty"`
+	End                       time.Time  `json:"end"`
+	PromptTokens              *int       `json:"prompt_tokens,omitempty"`     // As reported by the server
+	CompletionTokens          *int       `json:"completion_tokens,omitempty"` // As reported by the server
+	EstimatedPromptTokens     int        `json:"estimated_prompt_tokens"`
+	EstimatedCompletionTokens int        `json:"estimated_completion_tokens"`
+	FinishReason              string     `json:"finish_reason,omitempty"`
+	HTTPStatus                int        `json:"http_status,omitempty"`
+	ErrorClass                string     `json:"error_class,omitempty"`
+	Error                     string     `json:"error,omitempty"`
+	ChunkCount                int        `json:"chunk_count"`
+}
+
+// RequestsNdjson formats every request of the results as one JSON object per line.
+func RequestsNdjson(results []BenchmarkResult) (string, error) {
+	var lines []string
+	for _, result := range results {
+		for _, level := range result.Results {
+			for _, request := range level.Requests {
+				line := requestLine{
+					Scenario:                  result.Scenario,
+					BaseURL:                   result.BaseURL,
+					ModelName:                 result.ModelName,
+					Concurrency:               request.Concurrency,
+					Rate:                      request.Rate,
+					Start:                     request.Start.UTC(),
+					End:                       request.End.UTC(),
+					EstimatedPromptTokens:     request.EstimatedPromptTokens,
+					EstimatedCompletionTokens: request.EstimatedCompletionTokens,
+					FinishReason:              request.FinishReason,
+					HTTPStatus:                request.HTTPStatus,
+					ErrorClass:                request.ErrorClass,
+					Error:                     request.Error,
+					ChunkCount:                request.ChunkCount,
+				}
+				if !request.FirstToken.IsZero() {
+					firstToken := request.FirstToken.UTC()
+					line.FirstToken = &firstToken
+				}
+				if request.UsageReported {
+					line.PromptTokens = &request.PromptTokens
+					line.CompletionTokens = &request.CompletionTokens
+				}
+
+				data, err := json.Marshal(line)
+				if err != nil {
+					return "", fmt.Errorf("error marshalling JSON: %w", err)
+				}
+				lines = append(lines, string(data))
+			}
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
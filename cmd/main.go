@@ -1,15 +1,12 @@
 package main
 
 import (
-	"crypto/tls"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
+	"strings"
 
-	"github.com/Yoosu-L/llmapibenchmark/internal/api"
 	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
-	"github.com/sashabaranov/go-openai"
 	"github.com/spf13/pflag"
 )
 
@@ -18,17 +15,28 @@ const (
 )
 
 func main() {
-	baseURL := pflag.StringP("base-url", "u", "", "Base URL of the OpenAI API")
-	apiVersion := pflag.StringP("api-version", "v", "", "API version (api-version) query parameter value")
-	apiKey := pflag.StringP("api-key", "k", "", "API key for authentication")
-	model := pflag.StringP("model", "m", "", "Model to be used for the requests (optional)")
-	prompt := pflag.StringP("prompt", "p", defaultPrompt, "Prompt to be used for generating responses")
-	numWords := pflag.IntP("num-words", "n", 0, "If set to a value above 0 a random string with this length will be used as prompt")
-	concurrencyStr := pflag.StringP("concurrency", "c", "1,2,4,8,16,32,64,128", "Comma-separated list of concurrency levels")
-	maxTokens := pflag.IntP("max-tokens", "t", 512, "Maximum number of tokens to generate")
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "mock" {
+		runMock(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		runCompare(os.Args[2:])
+		return
+	}
+
+	var options Options
+	registerFlags(pflag.CommandLine, &options)
+	defaults := options
+	configPath := pflag.String("config", "", "YAML scenario file describing one or more benchmarks; flags override its values")
 	format := pflag.StringP("format", "f", "", "Output format (optional)")
+	output := pflag.StringP("output", "o", "", "Write the formatted results to this file instead of stdout")
+	htmlReport := pflag.String("html-report", "", "Write a self-contained HTML report with charts to this file")
+	requestsOut := pflag.String("requests-out", "", "Write every request's timings, token counts and outcome to this file as NDJSON")
+	requestsCsv := pflag.String("requests-csv", "", "Write one CSV row per request to this file")
+	traceOut := pflag.String("trace-out", "", "Write a Chrome trace of every request's queue, prefill and decode phases to this file, for Perfetto")
+	junitOut := pflag.String("junit-out", "", "Write the --assert results to this file as a JUnit XML report")
 	help := pflag.BoolP("help", "h", false, "Show this help message")
-	insecureSkipTLSVerify := pflag.Bool("insecure-skip-tls-verify", false, "Skip TLS certificate verification. Use with caution, this is insecure.")
 	pflag.Parse()
 
 	if *help {
@@ -37,108 +45,287 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Create benchmark
-	benchmark := Benchmark{}
-	benchmark.BaseURL = *baseURL
-	benchmark.ApiVersion = *apiVersion
-	benchmark.ApiKey = *apiKey
-	benchmark.ModelName = *model
-	benchmark.Prompt = *prompt
-	// Since each random word is roughly equivalent to 4 tokens (varies by model tokenizer),
-	// we divide the target token count by 4. This is an estimation.
-	benchmark.NumWords = *numWords / 4
-	if *numWords > 0 && benchmark.NumWords == 0 {
-		benchmark.NumWords = 1
+	// Resolve the scenarios to run, flags take precedence over the scenario file
+	scenarios := []Scenario{{Options: options}}
+	if *configPath != "" {
+		file, err := loadScenarioFile(*configPath, defaults)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		scenarios = file.Scenarios
+		for i := range scenarios {
+			overrideOptions(&scenarios[i].Options, options, pflag.CommandLine)
+		}
+		if !pflag.CommandLine.Changed("format") {
+			*format = file.Format
+		}
+		if !pflag.CommandLine.Changed("output") {
+			*output = file.Output
+		}
+		if !pflag.CommandLine.Changed("html-report") {
+			*htmlReport = file.HtmlReport
+		}
+		if !pflag.CommandLine.Changed("requests-out") {
+			*requestsOut = file.RequestsOut
+		}
+		if !pflag.CommandLine.Changed("requests-csv") {
+			*requestsCsv = file.RequestsCsv
+		}
+		if !pflag.CommandLine.Changed("trace-out") {
+			*traceOut = file.TraceOut
+		}
+		if !pflag.CommandLine.Changed("junit-out") {
+			*junitOut = file.JunitOut
+		}
 	}
-	benchmark.MaxTokens = *maxTokens
-
-	// Parse concurrency levels
-	concurrencyLevels, err := utils.ParseConcurrencyLevels(*concurrencyStr)
-	if err != nil {
-		log.Fatalf("Invalid concurrency levels: %v", err)
+	if *format != "" && *format != "json" && *format != "yaml" && *format != "csv" {
+		log.Fatalf("Invalid format specified: %s", *format)
 	}
-	benchmark.ConcurrencyLevels = concurrencyLevels
-
-	// Initialize OpenAI client
-	if *baseURL == "" {
-		log.Fatalf("--base-url is required")
+	for _, scenario := range scenarios {
+		// CSV has a row per level, A/B results have rounds instead
+		if *format == "csv" && scenario.AbBaseURL != "" {
+			log.Fatalf("%s--format csv cannot be combined with --ab-base-url, use json or yaml", scenarioPrefix(scenario))
+		}
 	}
-	config := openai.DefaultConfig(*apiKey)
-	config.BaseURL = *baseURL
-	config.APIVersion = *apiVersion
-
-	if *insecureSkipTLSVerify {
-		fmt.Fprintln(os.Stderr, "\n/!\\ WARNING: Skipping TLS certificate verification. This is insecure and should not be used in production. /!\\")
 
-		// Clone the default Transport to preserve its settings
-		defaultTransport, ok := http.DefaultTransport.(*http.Transport)
-		if !ok {
-			log.Fatalf("http.DefaultTransport is not an *http.Transport")
+	// Run every target of every scenario one after another, so they don't interfere
+	var results []BenchmarkResult
+	var checks []AssertionCheck
+	for _, scenario := range scenarios {
+		if *format == "" && scenario.Name != "" {
+			fmt.Printf("\033[36m\033[1mScenario: %s\033[0m\n", scenario.Name)
 		}
-		tr := defaultTransport.Clone()
-		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-		config.HTTPClient = &http.Client{Transport: tr}
-	}
+		scenarioTargets, err := targets(scenario.Options)
+		if err != nil {
+			log.Fatalf("%s%v", scenarioPrefix(scenario), err)
+		}
+		for _, target := range scenarioTargets {
+			if target.AbBaseURL != "" {
+				benchmark, other, err := newABBenchmarks(target)
+				if err != nil {
+					log.Fatalf("%s%v", scenarioPrefix(scenario), err)
+				}
+				result, err := benchmark.runAB(other, target.AbRounds, *format == "")
+				if err != nil {
+					log.Fatalf("%sError running A/B comparison: %v", scenarioPrefix(scenario), err)
+				}
+				result.Scenario = scenario.Name
+				results = append(results, result)
+				continue
+			}
 
-	client := openai.NewClientWithConfig(config)
+			benchmark, err := newBenchmark(target)
+			if err != nil {
+				log.Fatalf("%s%v", scenarioPrefix(scenario), err)
+			}
+			if *format == "" && len(scenarioTargets) > 1 {
+				fmt.Printf("\033[36m\033[1mTarget: %s at %s\033[0m\n", benchmark.ModelName, benchmark.BaseURL)
+			}
+			benchmark.ReportLabel = reportLabel(scenario.Name, benchmark.BaseURL, len(scenarioTargets) > 1)
 
-	// Discover model name if not provided
-	if *model == "" {
-		discoveredModel, err := api.GetFirstAvailableModel(client)
-		if err != nil {
-			log.Printf("Error discovering model: %v", err)
-			return
+			var result BenchmarkResult
+			if *format == "" {
+				result, err = benchmark.runCli()
+			} else {
+				result, err = benchmark.run()
+			}
+			if err != nil {
+				log.Fatalf("%sError running benchmark: %v", scenarioPrefix(scenario), err)
+			}
+			result.Scenario = scenario.Name
+			results = append(results, result)
+			for _, assertion := range benchmark.Assertions {
+				checks = append(checks, assertion.Check(benchmarkLabel(result), result.Results)...)
+			}
 		}
-		benchmark.ModelName = discoveredModel
 	}
 
-	// Determine input parameters and call benchmark function
-	if *prompt != "Write a long story, no less than 10,000 words, starting from a long, long time ago." {
-		benchmark.UseRandomInput = false
-	} else if *numWords != 0 {
-		benchmark.UseRandomInput = true
+	if *format == "" {
+		if len(results) > 1 {
+			printSummary(results)
+		}
 	} else {
-		benchmark.UseRandomInput = false
+		writeResults(results, *format, *output)
 	}
 
-	// Get input tokens
-	if benchmark.UseRandomInput {
-		_, _, promptTokens, err := api.AskOpenAiRandomInput(client, benchmark.ModelName, benchmark.NumWords, 4, nil)
-		if err != nil {
-			log.Fatalf("Error getting prompt tokens: %v", err)
+	if *htmlReport != "" {
+		var series []utils.ReportSeries
+		for i, label := range resultLabels(results) {
+			result := results[i]
+			if len(result.Results) == 0 {
+				continue
+			}
+			series = append(series, utils.ReportSeries{
+				Name:        label,
+				ModelName:   result.ModelName,
+				InputTokens: result.InputTokens,
+				MaxTokens:   result.MaxTokens,
+				Latency:     result.Latency,
+				OpenLoop:    result.Results[0].Rate > 0,
+				Results:     result.Results,
+			})
 		}
-		benchmark.InputTokens = promptTokens
-	} else {
-		_, _, promptTokens, err := api.AskOpenAi(client, benchmark.ModelName, *prompt, 4, nil)
+		if err := utils.SaveResultsToHTML(*htmlReport, series); err != nil {
+			log.Fatalf("%v", err)
+		}
+		if *format == "" {
+			fmt.Printf("HTML report saved to: %s\n\n", *htmlReport)
+		}
+	}
+	if *requestsOut != "" {
+		formatted, err := RequestsNdjson(results)
 		if err != nil {
-			log.Fatalf("Error getting prompt tokens: %v", err)
+			log.Fatalf("Error formatting requests: %v", err)
+		}
+		if err := os.WriteFile(*requestsOut, []byte(formatted+"\n"), 0644); err != nil {
+			log.Fatalf("Error writing %s: %v", *requestsOut, err)
 		}
-		benchmark.InputTokens = promptTokens
 	}
-
-	if *format == "" {
-		err := benchmark.runCli()
+	if *requestsCsv != "" {
+		formatted, err := RequestsCsv(results)
 		if err != nil {
-			log.Fatalf("Error running benchmark: %v", err)
+			log.Fatalf("Error formatting requests: %v", err)
 		}
-	} else {
-		result, err := benchmark.run()
+		if err := os.WriteFile(*requestsCsv, []byte(formatted+"\n"), 0644); err != nil {
+			log.Fatalf("Error writing %s: %v", *requestsCsv, err)
+		}
+	}
+	if *traceOut != "" {
+		formatted, err := Trace(results)
 		if err != nil {
-			log.Fatalf("Error running benchmark: %v", err)
+			log.Fatalf("Error formatting trace: %v", err)
 		}
+		if err := os.WriteFile(*traceOut, []byte(formatted+"\n"), 0644); err != nil {
+			log.Fatalf("Error writing %s: %v", *traceOut, err)
+		}
+	}
 
-		var output string
-		switch *format {
-		case "json":
-			output, err = result.Json()
-		case "yaml":
-			output, err = result.Yaml()
-		default:
-			log.Printf("Invalid format specified")
+	// Assertions decide the exit status once all output has been written
+	if len(checks) > 0 {
+		w := os.Stdout
+		if *format != "" {
+			// Keep stdout parseable
+			w = os.Stderr
 		}
-		if err != nil {
-			log.Fatalf("Error formatting benchmark result: %v", err)
+		failed := printAssertionChecks(w, checks)
+		if *junitOut != "" {
+			if err := writeJUnit(*junitOut, checks); err != nil {
+				log.Fatalf("Error writing %s: %v", *junitOut, err)
+			}
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+	}
+}
+
+// writeResults prints results in format, or writes them to output if set. A
+// single benchmark keeps its own JSON or YAML document, several are combined in
+// a report. CSV has a row per level of every benchmark either way.
+func writeResults(results []BenchmarkResult, format string, output string) {
+	var formatted string
+	var err error
+	report := Report{Benchmarks: results}
+	switch {
+	case format == "csv":
+		formatted, err = Csv(results)
+	case len(results) == 1 && format == "json":
+		formatted, err = results[0].Json()
+	case len(results) == 1:
+		formatted, err = results[0].Yaml()
+	case format == "json":
+		formatted, err = report.Json()
+	default:
+		formatted, err = report.Yaml()
+	}
+	if err != nil {
+		log.Fatalf("Error formatting benchmark result: %v", err)
+	}
+	if output != "" {
+		if err := os.WriteFile(output, []byte(formatted+"\n"), 0644); err != nil {
+			log.Fatalf("Error writing %s: %v", output, err)
+		}
+		return
+	}
+	fmt.Println(formatted)
+}
+
+// benchmarkLabel names a benchmark in assertion results.
+func benchmarkLabel(result BenchmarkResult) string {
+	if result.Scenario == "" {
+		return result.ModelName
+	}
+	return result.Scenario + " " + result.ModelName
+}
+
+// reportLabel tells apart the Markdown reports of one invocation: by scenario,
+// and by endpoint when a scenario has several targets.
+func reportLabel(scenario string, baseURL string, multipleTargets bool) string {
+	var parts []string
+	if scenario != "" {
+		parts = append(parts, scenario)
+	}
+	if multipleTargets {
+		endpoint := baseURL
+		if _, rest, found := strings.Cut(endpoint, "://"); found {
+			endpoint = rest
+		}
+		parts = append(parts, strings.TrimSuffix(endpoint, "/"))
+	}
+	return strings.Join(parts, "_")
+}
+
+// scenarioPrefix labels errors with the scenario they occurred in.
+func scenarioPrefix(scenario Scenario) string {
+	if scenario.Name == "" {
+		return ""
+	}
+	return fmt.Sprintf("Scenario %s: ", scenario.Name)
+}
+
+// resultLabels names each result by its scenario, and by its endpoint when
+// results come from more than one.
+func resultLabels(results []BenchmarkResult) []string {
+	endpoints := map[string]bool{}
+	for _, result := range results {
+		endpoints[result.BaseURL] = true
+	}
+	var labels []string
+	for _, result := range results {
+		var label []string
+		if result.Scenario != "" {
+			label = append(label, result.Scenario)
+		}
+		if len(endpoints) > 1 {
+			label = append(label, result.BaseURL)
+		}
+		labels = append(labels, strings.Join(label, " "))
+	}
+	return labels
+}
+
+// printSummary prints a table comparing every level of several benchmarks.
+func printSummary(results []BenchmarkResult) {
+	bold := "\033[1m"
+	green := "\033[32m"
+	reset := "\033[0m"
+
+	names := resultLabels(results)
+	var models []string
+	for _, result := range results {
+		models = append(models, result.ModelName)
+	}
+	table := utils.NewSummaryTable(names, models)
+
+	fmt.Printf("\n%s%sSummary%s\n", green, bold, reset)
+	header, separator := table.Header()
+	fmt.Printf("%s%s%s%s\n", green, bold, header, reset)
+	fmt.Printf("%s%s%s\n", green, separator, reset)
+	for i, result := range results {
+		for _, level := range result.Results {
+			fmt.Printf("%s%s%s\n", green, table.Row(names[i], models[i], level), reset)
 		}
-		fmt.Println(output)
 	}
+	fmt.Printf("%s%s%s\n", green, separator, reset)
+	fmt.Println("\n" + "\033[36m" + strings.Repeat("=", 80) + "\033[0m")
 }
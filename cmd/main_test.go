@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestReportLabel(t *testing.T) {
+	for _, test := range []struct {
+		scenario        string
+		baseURL         string
+		multipleTargets bool
+		want            string
+	}{
+		{"", "http://gpu:8000/v1", false, ""},
+		{"short", "http://gpu:8000/v1", false, "short"},
+		{"", "http://gpu:8000/v1/", true, "gpu:8000/v1"},
+		{"short", "https://api.example.com", true, "short_api.example.com"},
+	} {
+		if got := reportLabel(test.scenario, test.baseURL, test.multipleTargets); got != test.want {
+			t.Errorf("reportLabel(%q, %q, %v) = %q, want %q", test.scenario, test.baseURL, test.multipleTargets, got, test.want)
+		}
+	}
+}
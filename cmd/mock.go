@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/mock"
+	"github.com/spf13/pflag"
+)
+
+// runMock serves a mock OpenAI-compatible API with known timings, to validate the
+// benchmark's own math and to run it without a GPU or network.
+func runMock(args []string) {
+	flags := pflag.NewFlagSet("mock", pflag.ExitOnError)
+	listen := flags.StringP("listen", "l", "127.0.0.1:8080", "Address to listen on")
+	model := flags.StringP("model", "m", "mock-model", "Model name reported by /v1/models")
+	ttft := flags.Duration("ttft", 200*time.Millisecond, "Time to first token once a slot is free")
+	tokensPerSecond := flags.Float64("tps", 50, "Decode speed of each request in tokens/s")
+	jitter := flags.Float64("jitter", 0, "Relative per-request jitter applied to --ttft and --tps (0-1)")
+	slots := flags.Int("slots", 0, "Requests generated at once, excess requests queue (0 is unlimited)")
+	errorRate := flags.Float64("error-rate", 0, "Fraction of requests failing with HTTP 500 (0-1)")
+	omitUsage := flags.Bool("no-usage", false, "Never report token usage, even when the client asks for it")
+	help := flags.BoolP("help", "h", false, "Show this help message")
+	flags.Parse(args)
+
+	if *help {
+		fmt.Printf("Usage of %s mock:\n", os.Args[0])
+		flags.PrintDefaults()
+		os.Exit(0)
+	}
+	if *jitter < 0 || *jitter > 1 {
+		log.Fatalf("--jitter must be between 0 and 1")
+	}
+	if *errorRate < 0 || *errorRate > 1 {
+		log.Fatalf("--error-rate must be between 0 and 1")
+	}
+	if *tokensPerSecond < 0 || *slots < 0 || *ttft < 0 {
+		log.Fatalf("--tps, --slots and --ttft must not be negative")
+	}
+
+	server := mock.NewServer(mock.Config{
+		Model:           *model,
+		Ttft:            *ttft,
+		TokensPerSecond: *tokensPerSecond,
+		Jitter:          *jitter,
+		Slots:           *slots,
+		ErrorRate:       *errorRate,
+		OmitUsage:       *omitUsage,
+	})
+
+	log.Printf("Mock server listening on http://%s/v1 (model %s, TTFT %v, %.2f tokens/s)", *listen, *model, *ttft, *tokensPerSecond)
+	log.Fatal(http.ListenAndServe(*listen, server))
+}
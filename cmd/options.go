@@ -0,0 +1,396 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/api"
+	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
+	"github.com/Yoosu-L/llmapibenchmark/internal/workload"
+	"github.com/spf13/pflag"
+)
+
+// Options holds the settings of one benchmark run. Flags and scenario files both
+// fill it in, and its yaml keys match the flag names.
+type Options struct {
+	Provider              string        `yaml:"provider"`
+	BaseURL               string        `yaml:"base-url"`
+	ApiVersion            string        `yaml:"api-version"`
+	ApiKey                string        `yaml:"api-key"`
+	Model                 string        `yaml:"model"`
+	ModelRegex            string        `yaml:"model-regex"`
+	Prompt                string        `yaml:"prompt"`
+	NumWords              int           `yaml:"num-words"`
+	Dataset               string        `yaml:"dataset"`
+	DatasetMaxOutput      int           `yaml:"dataset-max-output"`
+	InputDist             string        `yaml:"input-dist"`
+	OutputDist            string        `yaml:"output-dist"`
+	Concurrency           string        `yaml:"concurrency"`
+	Rates                 string        `yaml:"rates"`
+	Arrival               string        `yaml:"arrival"`
+	LevelDuration         time.Duration `yaml:"level-duration"`
+	Warmup                time.Duration `yaml:"warmup"`
+	Seed                  int64         `yaml:"seed"`
+	MaxTokens             int           `yaml:"max-tokens"`
+	Percentiles           string        `yaml:"percentiles"`
+	MinUserSpeed          float64       `yaml:"min-user-tps"`
+	SloTtft               time.Duration `yaml:"slo-ttft"`
+	SloTpot               time.Duration `yaml:"slo-tpot"`
+	SloE2e                time.Duration `yaml:"slo-e2e"`
+	Search                bool          `yaml:"search"`
+	SearchMax             int           `yaml:"search-max"`
+	SearchMinSuccess      float64       `yaml:"search-min-success"`
+	Charts                bool          `yaml:"charts"`
+	Repeat                int           `yaml:"repeat"`
+	RepeatCi              float64       `yaml:"repeat-ci"`
+	RepeatMax             int           `yaml:"repeat-max"`
+	Assert                []string      `yaml:"assert"`
+	AbBaseURL             string        `yaml:"ab-base-url"`
+	AbModel               string        `yaml:"ab-model"`
+	AbRounds              int           `yaml:"ab-rounds"`
+	InsecureSkipTLSVerify bool          `yaml:"insecure-skip-tls-verify"`
+}
+
+// registerFlags defines a flag for every field of options, storing the defaults in it.
+func registerFlags(flags *pflag.FlagSet, options *Options) {
+	flags.StringVar(&options.Provider, "provider", api.ProviderOpenAI, "API protocol of the endpoint: "+strings.Join(api.ProviderNames(), ", "))
+	flags.StringVarP(&options.BaseURL, "base-url", "u", "", "Base URL of the API, comma-separated to benchmark several endpoints")
+	flags.StringVarP(&options.ApiVersion, "api-version", "v", "", "API version: the api-version query parameter, or the anthropic-version header with --provider anthropic")
+	flags.StringVarP(&options.ApiKey, "api-key", "k", "", "API key for authentication")
+	flags.StringVarP(&options.Model, "model", "m", "", "Model to be used for the requests, comma-separated to benchmark several models (optional)")
+	flags.StringVar(&options.ModelRegex, "model-regex", "", "Benchmark every model from /v1/models whose ID matches this regular expression")
+	flags.StringVarP(&options.Prompt, "prompt", "p", defaultPrompt, "Prompt to be used for generating responses")
+	flags.IntVarP(&options.NumWords, "num-words", "n", 0, "If set to a value above 0 a random string with this length will be used as prompt")
+	flags.StringVarP(&options.Dataset, "dataset", "d", "", "Prompt dataset file: ShareGPT conversations JSON, or JSONL of {prompt, expected_output_len}")
+	flags.IntVar(&options.DatasetMaxOutput, "dataset-max-output", 0, "Cap on each dataset sample's expected output length (0 uses the sample's own length)")
+	flags.StringVar(&options.InputDist, "input-dist", "", "Per-request input length distribution in tokens: fixed:N, uniform:MIN-MAX, normal:MEAN,STDDEV, lognormal:MEAN,STDDEV or hist:FILE")
+	flags.StringVar(&options.OutputDist, "output-dist", "", "Per-request output length distribution in tokens, same forms as --input-dist")
+	flags.StringVarP(&options.Concurrency, "concurrency", "c", "1,2,4,8,16,32,64,128", "Comma-separated list of concurrency levels")
+	flags.StringVarP(&options.Rates, "rates", "r", "", "Comma-separated list of open-loop request rates (requests/sec), replaces --concurrency when set")
+	flags.StringVar(&options.Arrival, "arrival", utils.ArrivalPoisson, "Inter-arrival process for --rates: poisson or constant")
+	flags.DurationVar(&options.LevelDuration, "level-duration", 0, "Measurement window of each level; keeps each concurrency level saturated for this long (default 1m for --rates)")
+	flags.DurationVar(&options.Warmup, "warmup", 0, "Warm-up period before the --level-duration measurement window starts")
+	flags.Int64Var(&options.Seed, "seed", 0, "Random seed for arrival times, dataset sampling, length distributions and the prompts they generate; plain --num-words prompts are only seeded in A/B mode (0 picks a random seed)")
+	flags.IntVarP(&options.MaxTokens, "max-tokens", "t", 512, "Maximum number of tokens to generate")
+	flags.StringVar(&options.Percentiles, "percentiles", "90,95,99", "Comma-separated percentiles reported for TTFT and end-to-end latency")
+	flags.Float64Var(&options.MinUserSpeed, "min-user-tps", 0, "Readable per-user decode speed (tokens/s); reports the highest concurrency whose median per-user speed meets it")
+	flags.DurationVar(&options.SloTtft, "slo-ttft", 0, "SLO on time to first token for goodput, e.g. 2s")
+	flags.DurationVar(&options.SloTpot, "slo-tpot", 0, "SLO on time per output token for goodput, e.g. 50ms")
+	flags.DurationVar(&options.SloE2e, "slo-e2e", 0, "SLO on end-to-end request latency for goodput, e.g. 30s")
+	flags.BoolVar(&options.Search, "search", false, "Search for the highest concurrency meeting the --slo-* limits at p95 instead of sweeping --concurrency")
+	flags.IntVar(&options.SearchMax, "search-max", 1024, "Upper bound on concurrency for --search")
+	flags.Float64Var(&options.SearchMinSuccess, "search-min-success", 1, "Minimum success rate (0-1) a level must reach to pass --search")
+	flags.BoolVar(&options.Charts, "charts", false, "Draw terminal charts of Gen TPS, TTFT and per-request latency after the results tables")
+	flags.IntVar(&options.Repeat, "repeat", 1, "Measure each level this many times and report the mean, stddev and 95% CI of every metric")
+	flags.Float64Var(&options.RepeatCi, "repeat-ci", 0, "Keep repeating each level until the 95% CI of Gen TPS is within this many percent of its mean")
+	flags.IntVar(&options.RepeatMax, "repeat-max", 10, "Upper bound on the trials per level for --repeat-ci")
+	flags.StringArrayVar(&options.Assert, "assert", nil, "Threshold checked against each level, e.g. \"conc=16:gen_tps>=400\" or \"p95_ttft<1.5s\" (repeatable)")
+	flags.StringVar(&options.AbBaseURL, "ab-base-url", "", "Compare this endpoint (B) against --base-url (A) in interleaved rounds with identical prompts")
+	flags.StringVar(&options.AbModel, "ab-model", "", "Model used on the --ab-base-url endpoint (defaults to --model)")
+	flags.IntVar(&options.AbRounds, "ab-rounds", 5, "Rounds per level in A/B mode, each measuring A and B back to back")
+	flags.BoolVar(&options.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false, "Skip TLS certificate verification. Use with caution, this is insecure.")
+}
+
+// newBenchmark validates options and prepares a benchmark from them: it parses
+// the load levels and workload, connects to the API, discovers the model if none
+// was given and measures the prompt's input tokens.
+func newBenchmark(options Options) (*Benchmark, error) {
+	// Create benchmark
+	benchmark := &Benchmark{}
+	benchmark.Provider = options.Provider
+	benchmark.BaseURL = options.BaseURL
+	benchmark.ModelName = options.Model
+	benchmark.Prompt = options.Prompt
+	// Since each random word is roughly equivalent to 4 tokens (varies by model tokenizer),
+	// we divide the target token count by 4. This is an estimation.
+	benchmark.NumWords = options.NumWords / 4
+	if options.NumWords > 0 && benchmark.NumWords == 0 {
+		benchmark.NumWords = 1
+	}
+	benchmark.MaxTokens = options.MaxTokens
+	benchmark.Charts = options.Charts
+
+	// Parse concurrency levels
+	concurrencyLevels, err := utils.ParseConcurrencyLevels(options.Concurrency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid concurrency levels: %v", err)
+	}
+	benchmark.ConcurrencyLevels = concurrencyLevels
+
+	// Parse latency percentiles
+	percentiles, err := utils.ParsePercentiles(options.Percentiles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid percentiles: %v", err)
+	}
+	benchmark.Percentiles = percentiles
+	benchmark.MinUserSpeed = options.MinUserSpeed
+	if options.SloTtft < 0 || options.SloTpot < 0 || options.SloE2e < 0 {
+		return nil, fmt.Errorf("SLOs must not be negative")
+	}
+	benchmark.Slo = utils.SLO{Ttft: options.SloTtft, Tpot: options.SloTpot, E2e: options.SloE2e}
+
+	// Parse open-loop request rates
+	levelDuration := options.LevelDuration
+	if options.Rates != "" {
+		rates, err := utils.ParseRates(options.Rates)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request rates: %v", err)
+		}
+		if options.Arrival != utils.ArrivalPoisson && options.Arrival != utils.ArrivalConstant {
+			return nil, fmt.Errorf("invalid arrival process: %s", options.Arrival)
+		}
+		if levelDuration == 0 {
+			levelDuration = time.Minute
+		}
+		benchmark.Rates = rates
+		benchmark.Arrival = options.Arrival
+	}
+	if levelDuration < 0 || options.Warmup < 0 {
+		return nil, fmt.Errorf("--level-duration and --warmup must not be negative")
+	}
+	if options.Warmup > 0 && levelDuration == 0 {
+		return nil, fmt.Errorf("--warmup requires --level-duration")
+	}
+	benchmark.LevelDuration = levelDuration
+	benchmark.Warmup = options.Warmup
+	benchmark.Seed = options.Seed
+	if benchmark.Seed == 0 {
+		benchmark.Seed = time.Now().UnixNano()
+	}
+
+	// Capacity search
+	if options.Search {
+		if len(benchmark.Rates) > 0 {
+			return nil, fmt.Errorf("--search cannot be combined with --rates")
+		}
+		if options.SearchMax <= 0 {
+			return nil, fmt.Errorf("--search-max must be positive")
+		}
+		if options.SearchMinSuccess < 0 || options.SearchMinSuccess > 1 {
+			return nil, fmt.Errorf("--search-min-success must be between 0 and 1")
+		}
+		if !benchmark.Slo.Enabled() {
+			return nil, fmt.Errorf("--search requires at least one of --slo-ttft, --slo-tpot and --slo-e2e")
+		}
+		benchmark.Search = true
+		benchmark.SearchMax = options.SearchMax
+		benchmark.SearchMinSuccess = options.SearchMinSuccess
+		// The search checks p95, make sure it is computed
+		if !slices.Contains(benchmark.Percentiles, searchPercentile) {
+			benchmark.Percentiles = append(benchmark.Percentiles, searchPercentile)
+			slices.Sort(benchmark.Percentiles)
+		}
+	}
+
+	// Repeated trials
+	if options.Repeat < 1 {
+		return nil, fmt.Errorf("--repeat must be at least 1")
+	}
+	if options.RepeatCi < 0 {
+		return nil, fmt.Errorf("--repeat-ci must not be negative")
+	}
+	if options.RepeatCi > 0 && options.RepeatMax < max(options.Repeat, 2) {
+		return nil, fmt.Errorf("--repeat-max must be at least --repeat and 2")
+	}
+	benchmark.Repeat = options.Repeat
+	benchmark.RepeatCi = options.RepeatCi
+	benchmark.RepeatMax = options.RepeatMax
+
+	// Parse assertions, computing the percentiles they check
+	for _, text := range options.Assert {
+		assertion, err := ParseAssertion(text)
+		if err != nil {
+			return nil, err
+		}
+		if assertion.Percentile > 0 && !slices.Contains(benchmark.Percentiles, assertion.Percentile) {
+			benchmark.Percentiles = append(benchmark.Percentiles, assertion.Percentile)
+			slices.Sort(benchmark.Percentiles)
+		}
+		benchmark.Assertions = append(benchmark.Assertions, assertion)
+	}
+
+	// Initialize the API client
+	client, err := newProvider(options)
+	if err != nil {
+		return nil, err
+	}
+	benchmark.Client = client
+
+	// Discover model name if not provided
+	if options.Model == "" {
+		discoveredModel, err := api.GetFirstAvailableModel(client)
+		if err != nil {
+			return nil, fmt.Errorf("error discovering model: %v", err)
+		}
+		benchmark.ModelName = discoveredModel
+	}
+
+	// Determine input parameters and call benchmark function
+	if options.Prompt != defaultPrompt {
+		benchmark.UseRandomInput = false
+	} else if options.NumWords != 0 {
+		benchmark.UseRandomInput = true
+	} else {
+		benchmark.UseRandomInput = false
+	}
+
+	// Load the prompt dataset
+	if options.Dataset != "" {
+		dataset, err := workload.LoadDataset(options.Dataset)
+		if err != nil {
+			return nil, fmt.Errorf("error loading dataset: %v", err)
+		}
+		benchmark.DatasetPath = options.Dataset
+		benchmark.Dataset = dataset
+		benchmark.DatasetMaxOutput = options.DatasetMaxOutput
+	}
+
+	// Parse input/output length distributions
+	if (options.InputDist != "" || options.OutputDist != "") && options.Dataset != "" {
+		return nil, fmt.Errorf("--input-dist and --output-dist cannot be combined with --dataset")
+	}
+	if options.InputDist != "" {
+		benchmark.InputLength, err = workload.ParseLength(options.InputDist)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input length distribution: %v", err)
+		}
+		benchmark.InputDist = options.InputDist
+	}
+	if options.OutputDist != "" {
+		benchmark.OutputLength, err = workload.ParseLength(options.OutputDist)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output length distribution: %v", err)
+		}
+		benchmark.OutputDist = options.OutputDist
+	}
+
+	// Get input tokens
+	if len(benchmark.Dataset) > 0 {
+		// Prompts vary per request, so report the estimated average
+		benchmark.InputTokens = workload.MeanPromptTokens(benchmark.Dataset)
+	} else {
+		prompt := options.Prompt
+		if benchmark.UseRandomInput {
+			prompt = api.GenerateRandomPhrase(benchmark.NumWords)
+		}
+		stats, err := client.Stream(benchmark.ModelName, prompt, 4, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error getting prompt tokens: %v", err)
+		}
+		benchmark.InputTokens = stats.PromptTokens
+	}
+
+	return benchmark, nil
+}
+
+// newProvider returns a client for the endpoint in options, speaking the
+// protocol selected with --provider.
+func newProvider(options Options) (api.Provider, error) {
+	if options.BaseURL == "" {
+		return nil, fmt.Errorf("--base-url is required")
+	}
+	httpClient, err := newHTTPClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return api.NewProvider(options.Provider, api.Endpoint{
+		BaseURL:    options.BaseURL,
+		ApiKey:     options.ApiKey,
+		ApiVersion: options.ApiVersion,
+		HTTPClient: httpClient,
+	})
+}
+
+// newHTTPClient returns the HTTP client to reach the endpoint with, or nil for the default one.
+func newHTTPClient(options Options) (*http.Client, error) {
+	if !options.InsecureSkipTLSVerify {
+		return nil, nil
+	}
+	fmt.Fprintln(os.Stderr, "\n/!\\ WARNING: Skipping TLS certificate verification. This is insecure and should not be used in production. /!\\")
+
+	// Clone the default Transport to preserve its settings
+	defaultTransport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("http.DefaultTransport is not an *http.Transport")
+	}
+	tr := defaultTransport.Clone()
+	tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return &http.Client{Transport: tr}, nil
+}
+
+// targets expands options into one set of options per endpoint and model: the
+// comma-separated base URLs crossed with the comma-separated models, or with
+// the models each endpoint lists that match --model-regex.
+func targets(options Options) ([]Options, error) {
+	baseURLs := splitList(options.BaseURL)
+	models := splitList(options.Model)
+	if options.ModelRegex != "" && len(models) > 0 {
+		return nil, fmt.Errorf("--model and --model-regex cannot be combined")
+	}
+	if options.AbBaseURL != "" && (len(baseURLs) > 1 || len(models) > 1 || options.ModelRegex != "") {
+		return nil, fmt.Errorf("--ab-base-url compares a single --base-url and --model")
+	}
+	if options.AbBaseURL != "" && len(options.Assert) > 0 {
+		// A/B results hold rounds instead of levels, which assertions can't check
+		return nil, fmt.Errorf("--assert cannot be combined with --ab-base-url")
+	}
+	if len(baseURLs) == 0 {
+		// Let newBenchmark report the missing base URL
+		return []Options{options}, nil
+	}
+
+	var pattern *regexp.Regexp
+	if options.ModelRegex != "" {
+		var err error
+		pattern, err = regexp.Compile(options.ModelRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid model regex: %v", err)
+		}
+	}
+
+	var expanded []Options
+	for _, baseURL := range baseURLs {
+		target := options
+		target.BaseURL = baseURL
+		target.ModelRegex = ""
+
+		targetModels := models
+		if pattern != nil {
+			client, err := newProvider(target)
+			if err != nil {
+				return nil, err
+			}
+			targetModels, err = api.GetModelsMatching(client, pattern)
+			if err != nil {
+				return nil, fmt.Errorf("error discovering models at %s: %v", baseURL, err)
+			}
+		}
+		if len(targetModels) == 0 {
+			// Discover the model later
+			expanded = append(expanded, target)
+			continue
+		}
+		for _, model := range targetModels {
+			target.Model = model
+			expanded = append(expanded, target)
+		}
+	}
+	return expanded, nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/spf13/pflag"
+	"go.yaml.in/yaml/v4"
+)
+
+// ScenarioFile is the layout of a --config file. Defaults apply to every
+// scenario, and each scenario overrides them with its own settings.
+type ScenarioFile struct {
+	Format      string     `yaml:"format"`
+	Output      string     `yaml:"output"`
+	HtmlReport  string     `yaml:"html-report"`
+	RequestsOut string     `yaml:"requests-out"`
+	RequestsCsv string     `yaml:"requests-csv"`
+	TraceOut    string     `yaml:"trace-out"`
+	JunitOut    string     `yaml:"junit-out"`
+	Defaults    Options    `yaml:"defaults"`
+	Scenarios   []Scenario `yaml:"scenarios"`
+}
+
+// Scenario is a named benchmark run described in a scenario file.
+type Scenario struct {
+	Name    string `yaml:"name"`
+	Options `yaml:",inline"`
+}
+
+// loadScenarioFile reads a scenario file and resolves every scenario's options
+// on top of base. A file without scenarios runs its defaults as one unnamed
+// scenario.
+func loadScenarioFile(path string, base Options) (ScenarioFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ScenarioFile{}, fmt.Errorf("error reading scenario file: %v", err)
+	}
+
+	// Reject unknown keys first so typos don't silently fall back to defaults
+	var file ScenarioFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return ScenarioFile{}, fmt.Errorf("error parsing scenario file %s: %v", path, err)
+	}
+
+	// Decode again on top of the base options, so keys a scenario leaves out
+	// keep the value from the defaults section or the flag defaults
+	var nodes struct {
+		Defaults  yaml.Node   `yaml:"defaults"`
+		Scenarios []yaml.Node `yaml:"scenarios"`
+	}
+	if err := yaml.Unmarshal(data, &nodes); err != nil {
+		return ScenarioFile{}, fmt.Errorf("error parsing scenario file %s: %v", path, err)
+	}
+	file.Defaults = base
+	if !nodes.Defaults.IsZero() {
+		if err := nodes.Defaults.Decode(&file.Defaults); err != nil {
+			return ScenarioFile{}, fmt.Errorf("error parsing defaults in %s: %v", path, err)
+		}
+	}
+
+	file.Scenarios = nil
+	for i, node := range nodes.Scenarios {
+		scenario := Scenario{Options: file.Defaults}
+		if err := node.Decode(&scenario); err != nil {
+			return ScenarioFile{}, fmt.Errorf("error parsing scenario %d in %s: %v", i+1, path, err)
+		}
+		if scenario.Name == "" {
+			scenario.Name = fmt.Sprintf("scenario-%d", i+1)
+		}
+		file.Scenarios = append(file.Scenarios, scenario)
+	}
+	if len(file.Scenarios) == 0 {
+		file.Scenarios = []Scenario{{Options: file.Defaults}}
+	}
+
+	return file, nil
+}
+
+// overrideOptions copies the options whose flags were set on the command line
+// from flagged into options.
+func overrideOptions(options *Options, flagged Options, flags *pflag.FlagSet) {
+	dst := reflect.ValueOf(options).Elem()
+	src := reflect.ValueOf(flagged)
+	for i := 0; i < dst.NumField(); i++ {
+		name := dst.Type().Field(i).Tag.Get("yaml")
+		if flags.Changed(name) {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// parseFlags parses args into options on a fresh flag set, as main does.
+func parseFlags(t *testing.T, args ...string) (Options, *pflag.FlagSet) {
+	t.Helper()
+	var options Options
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	registerFlags(flags, &options)
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return options, flags
+}
+
+func TestOptionsMatchFlags(t *testing.T) {
+	_, flags := parseFlags(t)
+	fields := reflect.TypeOf(Options{})
+	for i := 0; i < fields.NumField(); i++ {
+		name := fields.Field(i).Tag.Get("yaml")
+		if flags.Lookup(name) == nil {
+			t.Errorf("Options.%s has yaml key %q, which is not a flag", fields.Field(i).Name, name)
+		}
+	}
+}
+
+func TestOverrideOptions(t *testing.T) {
+	scenario := Options{BaseURL: "http://scenario/v1", Concurrency: "1,2", MaxTokens: 256, NumWords: 100, Search: true}
+	for _, test := range []struct {
+		name string
+		args []string
+		want Options
+	}{
+		{
+			name: "no flags",
+			want: scenario,
+		},
+		{
+			name: "set flags win",
+			args: []string{"-t", "128", "--concurrency=8"},
+			want: Options{BaseURL: "http://scenario/v1", Concurrency: "8", MaxTokens: 128, NumWords: 100, Search: true},
+		},
+		{
+			name: "flags set to their default still win",
+			args: []string{"--search=false", "-n", "0"},
+			want: Options{BaseURL: "http://scenario/v1", Concurrency: "1,2", MaxTokens: 256, Search: false},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			flagged, flags := parseFlags(t, test.args...)
+			options := scenario
+			overrideOptions(&options, flagged, flags)
+			if !reflect.DeepEqual(options, test.want) {
+				t.Errorf("options = %+v, want %+v", options, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadScenarioFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	content := `
+format: json
+defaults:
+  base-url: http://gpu:8000/v1
+  max-tokens: 128
+scenarios:
+  - name: short
+    concurrency: "1,4"
+  - max-tokens: 1024
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	base, _ := parseFlags(t)
+	file, err := loadScenarioFile(path, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Format != "json" || len(file.Scenarios) != 2 {
+		t.Fatalf("format %q with %d scenarios, want json with 2", file.Format, len(file.Scenarios))
+	}
+	short, long := file.Scenarios[0], file.Scenarios[1]
+	if short.Name != "short" || short.Concurrency != "1,4" || short.MaxTokens != 128 || short.BaseURL != "http://gpu:8000/v1" {
+		t.Errorf("first scenario = %+v", short)
+	}
+	if long.Name != "scenario-2" || long.Concurrency != base.Concurrency || long.MaxTokens != 1024 {
+		t.Errorf("second scenario = %+v", long)
+	}
+
+	if err := os.WriteFile(path, []byte("defaults:\n  max-token: 128\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadScenarioFile(path, base); err == nil {
+		t.Error("unknown key accepted")
+	}
+}
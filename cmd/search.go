@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
+)
+
+// searchPercentile is the percentile of TTFT and TPOT checked against the SLOs.
+const searchPercentile = 95
+
+type SearchResult struct {
+	// Highest concurrency that met every objective, 0 if even a single request did not
+	Capacity       int     `json:"capacity" yaml:"capacity"`
+	MaxConcurrency int     `json:"max_concurrency" yaml:"max-concurrency"`
+	MinSuccessRate float64 `json:"min_success_rate" yaml:"min-success-rate"`
+}
+
+// sweep measures every level of the benchmark, calling report after each one. In
+// search mode the levels are chosen by searchCapacity and a SearchResult is returned.
+func (benchmark *Benchmark) sweep(latency float64, clearProgress bool, report func(utils.SpeedResult)) ([]utils.SpeedResult, *SearchResult, error) {
+	var results []utils.SpeedResult
+	measure := func(level Level) (utils.SpeedResult, error) {
+		result, err := benchmark.measureTrials(latency, level, clearProgress)
+		if err != nil {
+			return result, fmt.Errorf("%v: %v", level, err)
+		}
+		report(result)
+		results = append(results, result)
+		return result, nil
+	}
+
+	if benchmark.Search {
+		search, err := benchmark.searchCapacity(measure)
+		sort.Slice(results, func(i, j int) bool { return results[i].Concurrency < results[j].Concurrency })
+		return results, search, err
+	}
+
+	for _, level := range benchmark.levels() {
+		if _, err := measure(level); err != nil {
+			return results, nil, err
+		}
+	}
+	return results, nil, nil
+}
+
+// searchCapacity finds the highest concurrency at which the SLOs still hold. It
+// doubles concurrency from 1 until a level fails or SearchMax is reached, then
+// binary-searches between the last passing and the first failing level.
+func (benchmark *Benchmark) searchCapacity(measure func(Level) (utils.SpeedResult, error)) (*SearchResult, error) {
+	search := &SearchResult{MaxConcurrency: benchmark.SearchMax, MinSuccessRate: benchmark.SearchMinSuccess}
+
+	probe := func(concurrency int) (bool, error) {
+		result, err := measure(Level{Concurrency: concurrency})
+		if err != nil {
+			return false, err
+		}
+		return benchmark.meetsSearchObjectives(result), nil
+	}
+
+	passing, failing := 0, 0
+	for concurrency := 1; ; concurrency = min(concurrency*2, benchmark.SearchMax) {
+		ok, err := probe(concurrency)
+		if err != nil {
+			return search, err
+		}
+		if !ok {
+			failing = concurrency
+			break
+		}
+		passing = concurrency
+		if concurrency == benchmark.SearchMax {
+			break
+		}
+	}
+
+	for failing > 0 && failing-passing > 1 {
+		mid := (passing + failing) / 2
+		ok, err := probe(mid)
+		if err != nil {
+			return search, err
+		}
+		if ok {
+			passing = mid
+		} else {
+			failing = mid
+		}
+	}
+
+	search.Capacity = passing
+	return search, nil
+}
+
+// meetsSearchObjectives reports whether a level met the minimum success rate and
+// the p95 TTFT, TPOT and end-to-end latency SLOs that are set. A level without
+// samples for a set SLO fails it.
+func (benchmark *Benchmark) meetsSearchObjectives(result utils.SpeedResult) bool {
+	slo := benchmark.Slo
+	if result.SuccessRate < benchmark.SearchMinSuccess {
+		return false
+	}
+	if slo.Ttft > 0 && !withinSearchLimit(result.Ttft, slo.Ttft.Seconds()) {
+		return false
+	}
+	if slo.Tpot > 0 && !withinSearchLimit(result.Tpot, slo.Tpot.Seconds()*1000) {
+		return false
+	}
+	if slo.E2e > 0 && !withinSearchLimit(result.E2eLatency, slo.E2e.Seconds()) {
+		return false
+	}
+	return true
+}
+
+// withinSearchLimit reports whether the p95 of distribution is known and at most limit.
+func withinSearchLimit(distribution utils.Distribution, limit float64) bool {
+	value, ok := distribution.Percentiles[utils.PercentileKey(searchPercentile)]
+	return ok && value <= limit
+}
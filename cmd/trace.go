@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
+)
+
+// traceEvent is an event of the Chrome trace event format, as read by Perfetto
+// and chrome://tracing. Timestamps and durations are in microseconds.
+type traceEvent struct {
+	Name      string         `json:"name"`
+	Category  string         `json:"cat,omitempty"`
+	Phase     string         `json:"ph"`
+	Timestamp float64        `json:"ts"`
+	Duration  float64        `json:"dur"`
+	Pid       int            `json:"pid"`
+	Tid       int            `json:"tid"`
+	Args      map[string]any `json:"args,omitempty"`
+}
+
+type traceFile struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// Trace formats the requests of the results as a Chrome trace. Every level is a
+// process with one thread per in-flight request slot, and every request shows
+// its queue, prefill and decode phases on the slot it occupied.
+func Trace(results []BenchmarkResult) (string, error) {
+	var origin time.Time
+	for _, result := range results {
+		for _, level := range result.Results {
+			for _, request := range level.Requests {
+				if origin.IsZero() || requestBegin(request).Before(origin) {
+					origin = requestBegin(request)
+				}
+			}
+		}
+	}
+	micros := func(t time.Time) float64 {
+		return float64(t.Sub(origin).Nanoseconds()) / 1e3
+	}
+
+	events := []traceEvent{}
+	pid := 0
+	labels := resultLabels(results)
+	for i, result := range results {
+		for _, level := range result.Results {
+			if len(level.Requests) == 0 {
+				continue
+			}
+			pid++
+			name := fmt.Sprintf("%s conc %d", result.ModelName, level.Concurrency)
+			if level.Rate > 0 {
+				name = fmt.Sprintf("%s rate %g", result.ModelName, level.Rate)
+			}
+			if labels[i] != "" {
+				name = labels[i] + " " + name
+			}
+			events = append(events,
+				traceEvent{Name: "process_name", Phase: "M", Pid: pid, Args: map[string]any{"name": name}},
+				traceEvent{Name: "process_sort_index", Phase: "M", Pid: pid, Args: map[string]any{"sort_index": pid}},
+			)
+
+			slots := assignSlots(level.Requests)
+			for slot := range slots.count {
+				events = append(events, traceEvent{Name: "thread_name", Phase: "M", Pid: pid, Tid: slot + 1, Args: map[string]any{"name": fmt.Sprintf("slot %d", slot+1)}})
+			}
+
+			for j, request := range level.Requests {
+				tid := slots.slot[j] + 1
+				span := func(name string, from, to time.Time, args map[string]any) {
+					events = append(events, traceEvent{
+						Name:      name,
+						Category:  "request",
+						Phase:     "X",
+						Timestamp: micros(from),
+						Duration:  float64(to.Sub(from).Nanoseconds()) / 1e3,
+						Pid:       pid,
+						Tid:       tid,
+						Args:      args,
+					})
+				}
+
+				if !request.Scheduled.IsZero() && request.Start.After(request.Scheduled) {
+					span("queue", request.Scheduled, request.Start, nil)
+				}
+				args := map[string]any{
+					"request":     j,
+					"http_status": request.HTTPStatus,
+				}
+				if request.UsageReported {
+					args["prompt_tokens"] = request.PromptTokens
+					args["completion_tokens"] = request.CompletionTokens
+				} else {
+					args["estimated_prompt_tokens"] = request.EstimatedPromptTokens
+					args["estimated_completion_tokens"] = request.EstimatedCompletionTokens
+				}
+				if request.FinishReason != "" {
+					args["finish_reason"] = request.FinishReason
+				}
+				if request.Error != "" {
+					args["error_class"] = request.ErrorClass
+					args["error"] = request.Error
+				}
+				if request.FirstToken.IsZero() {
+					// No token arrived, the whole request was spent waiting for one
+					name := "prefill"
+					if request.Error != "" {
+						name = "error"
+					}
+					span(name, request.Start, request.End, args)
+					continue
+				}
+				span("prefill", request.Start, request.FirstToken, args)
+				span("decode", request.FirstToken, request.End, args)
+			}
+		}
+	}
+
+	data, err := json.Marshal(traceFile{TraceEvents: events, DisplayTimeUnit: "ms"})
+	if err != nil {
+		return "", fmt.Errorf("error marshalling JSON: %w", err)
+	}
+	return string(data), nil
+}
+
+// requestBegin returns when a request entered the trace, its scheduled time if known.
+func requestBegin(request utils.RequestRecord) time.Time {
+	if !request.Scheduled.IsZero() && request.Scheduled.Before(request.Start) {
+		return request.Scheduled
+	}
+	return request.Start
+}
+
+type traceSlots struct {
+	slot  []int // Slot of each request
+	count int
+}
+
+// assignSlots packs requests onto the fewest slots so that requests sharing a
+// slot never overlap, reusing the lowest free slot.
+func assignSlots(requests []utils.RequestRecord) traceSlots {
+	order := make([]int, len(requests))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return requestBegin(requests[order[a]]).Before(requestBegin(requests[order[b]]))
+	})
+
+	slots := traceSlots{slot: make([]int, len(requests))}
+	var free []time.Time // When each slot frees up
+	for _, i := range order {
+		begin := requestBegin(requests[i])
+		chosen := -1
+		for slot, end := range free {
+			if !end.After(begin) {
+				chosen = slot
+				break
+			}
+		}
+		if chosen < 0 {
+			chosen = len(free)
+			free = append(free, time.Time{})
+		}
+		free[chosen] = requests[i].End
+		slots.slot[i] = chosen
+	}
+	slots.count = len(free)
+	return slots
+}
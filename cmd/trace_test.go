@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
+)
+
+var traceOrigin = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// span returns a request sent at start and ended at end, in milliseconds after traceOrigin.
+func span(start, end int) utils.RequestRecord {
+	return utils.RequestRecord{
+		Start: traceOrigin.Add(time.Duration(start) * time.Millisecond),
+		End:   traceOrigin.Add(time.Duration(end) * time.Millisecond),
+	}
+}
+
+func TestAssignSlots(t *testing.T) {
+	queued := span(50, 60)
+	queued.Scheduled = traceOrigin.Add(5 * time.Millisecond)
+	for _, test := range []struct {
+		name     string
+		requests []utils.RequestRecord
+		slots    []int
+		count    int
+	}{
+		{"none", nil, []int{}, 0},
+		{"sequential requests share a slot", []utils.RequestRecord{span(0, 10), span(10, 20), span(30, 40)}, []int{0, 0, 0}, 1},
+		{"overlapping requests", []utils.RequestRecord{span(0, 100), span(10, 20), span(15, 30)}, []int{0, 1, 2}, 3},
+		{"lowest free slot is reused", []utils.RequestRecord{span(0, 100), span(10, 20), span(25, 30)}, []int{0, 1, 1}, 2},
+		{"unsorted input", []utils.RequestRecord{span(25, 30), span(0, 100), span(10, 20)}, []int{1, 0, 1}, 2},
+		{"queueing occupies the slot", []utils.RequestRecord{span(0, 10), queued}, []int{0, 1}, 2},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			slots := assignSlots(test.requests)
+			if !reflect.DeepEqual(slots.slot, test.slots) || slots.count != test.count {
+				t.Errorf("slots = %v of %d, want %v of %d", slots.slot, slots.count, test.slots, test.count)
+			}
+		})
+	}
+}
+
+func TestTrace(t *testing.T) {
+	first := span(0, 100)
+	first.FirstToken = traceOrigin.Add(40 * time.Millisecond)
+	failed := span(10, 20)
+	failed.Error = "status code: 500"
+	results := []BenchmarkResult{{ModelName: "m", Results: []utils.SpeedResult{{Concurrency: 2, Requests: []utils.RequestRecord{first, failed}}}}}
+
+	formatted, err := Trace(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trace struct {
+		TraceEvents []struct {
+			Name  string  `json:"name"`
+			Phase string  `json:"ph"`
+			Ts    float64 `json:"ts"`
+			Dur   float64 `json:"dur"`
+			Tid   int     `json:"tid"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal([]byte(formatted), &trace); err != nil {
+		t.Fatal(err)
+	}
+	type spanEvent struct {
+		name    string
+		ts, dur float64
+		slot    int
+	}
+	var spans []spanEvent
+	for _, event := range trace.TraceEvents {
+		if event.Phase == "X" {
+			spans = append(spans, spanEvent{event.Name, event.Ts, event.Dur, event.Tid})
+		}
+	}
+	// Threads are numbered from 1, one per slot
+	want := []spanEvent{{"prefill", 0, 40000, 1}, {"decode", 40000, 60000, 1}, {"error", 10000, 10000, 2}}
+	if !reflect.DeepEqual(spans, want) {
+		t.Errorf("spans = %+v, want %+v", spans, want)
+	}
+}
@@ -1,11 +1,19 @@
 package main
 
-import "github.com/Yoosu-L/llmapibenchmark/internal/utils"
+import (
+	"fmt"
+	"time"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/api"
+	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
+	"github.com/Yoosu-L/llmapibenchmark/internal/workload"
+)
 
 type Benchmark struct {
+	ReportLabel       string // Added to the Markdown report's file name, empty for a single target
+	Provider          string
 	BaseURL           string
-	ApiVersion        string
-	ApiKey            string
+	Client            api.Provider
 	ModelName         string
 	Prompt            string
 	InputTokens       int
@@ -13,12 +21,87 @@ type Benchmark struct {
 	ConcurrencyLevels []int
 	UseRandomInput    bool
 	NumWords          int
+	SeedPrompts       bool // Draw random input from Seed instead of the clock, so other runs can repeat it
+	Rates             []float64
+	Arrival           string
+	LevelDuration     time.Duration
+	Warmup            time.Duration
+	Seed              int64
+	Percentiles       []float64
+	MinUserSpeed      float64
+	Slo               utils.SLO
+	Search            bool
+	SearchMax         int
+	SearchMinSuccess  float64
+	DatasetPath       string
+	Dataset           []workload.Sample
+	DatasetMaxOutput  int
+	InputDist         string
+	InputLength       workload.Length
+	OutputDist        string
+	OutputLength      workload.Length
+	Charts            bool
+	Repeat            int
+	RepeatCi          float64
+	RepeatMax         int
+	Assertions        []Assertion
+}
+
+// Level is a single step of a benchmark sweep: either a closed burst of
+// Concurrency requests or an open-loop arrival Rate in requests/sec.
+type Level struct {
+	Concurrency int
+	Rate        float64
+}
+
+func (level Level) String() string {
+	if level.Rate > 0 {
+		return fmt.Sprintf("rate %g", level.Rate)
+	}
+	return fmt.Sprintf("concurrency %d", level.Concurrency)
 }
 
 type BenchmarkResult struct {
-	ModelName   string              `json:"model_name" yaml:"model-name"`
-	InputTokens int                 `json:"input_tokens" yaml:"input-tokens"`
-	MaxTokens   int                 `json:"output_tokens" yaml:"output-tokens"` // Historically been called Output Tokens
-	Latency     float64             `json:"latency" yaml:"latency"`
-	Results     []utils.SpeedResult `json:"results" yaml:"results"`
+	Scenario               string              `json:"scenario,omitempty" yaml:"scenario,omitempty"`
+	Provider               string              `json:"provider" yaml:"provider"`
+	BaseURL                string              `json:"base_url" yaml:"base-url"`
+	ModelName              string              `json:"model_name" yaml:"model-name"`
+	InputTokens            int                 `json:"input_tokens" yaml:"input-tokens"`
+	MaxTokens              int                 `json:"output_tokens" yaml:"output-tokens"` // Historically been called Output Tokens
+	Latency                float64             `json:"latency" yaml:"latency"`
+	Dataset                string              `json:"dataset,omitempty" yaml:"dataset,omitempty"`
+	InputDist              string              `json:"input_dist,omitempty" yaml:"input-dist,omitempty"`
+	OutputDist             string              `json:"output_dist,omitempty" yaml:"output-dist,omitempty"`
+	Arrival                string              `json:"arrival,omitempty" yaml:"arrival,omitempty"`
+	Seed                   int64               `json:"seed,omitempty" yaml:"seed,omitempty"`
+	LevelDuration          float64             `json:"level_duration,omitempty" yaml:"level-duration,omitempty"`
+	Warmup                 float64             `json:"warmup,omitempty" yaml:"warmup,omitempty"`
+	Repeat                 int                 `json:"repeat,omitempty" yaml:"repeat,omitempty"`
+	RepeatCi               float64             `json:"repeat_ci,omitempty" yaml:"repeat-ci,omitempty"`
+	MinUserSpeed           float64             `json:"min_user_speed,omitempty" yaml:"min-user-speed,omitempty"`
+	MaxReadableConcurrency int                 `json:"max_readable_concurrency,omitempty" yaml:"max-readable-concurrency,omitempty"` // Highest concurrency whose median per-user decode speed met MinUserSpeed
+	Slo                    *SloSpec            `json:"slo,omitempty" yaml:"slo,omitempty"`
+	Search                 *SearchResult       `json:"search,omitempty" yaml:"search,omitempty"`
+	AB                     *ABResult           `json:"ab,omitempty" yaml:"ab,omitempty"` // Set in A/B mode, where Results is nil
+	Results                []utils.SpeedResult `json:"results" yaml:"results"`
+}
+
+// Report combines the results of several benchmarks run in one invocation.
+type Report struct {
+	Benchmarks []BenchmarkResult `json:"benchmarks" yaml:"benchmarks"`
+}
+
+// SloSpec records the SLOs goodput was measured against, in seconds.
+type SloSpec struct {
+	Ttft float64 `json:"ttft,omitempty" yaml:"ttft,omitempty"`
+	Tpot float64 `json:"tpot,omitempty" yaml:"tpot,omitempty"`
+	E2e  float64 `json:"e2e,omitempty" yaml:"e2e,omitempty"`
+}
+
+func newSloSpec(slo utils.SLO) *SloSpec {
+	return &SloSpec{
+		Ttft: slo.Ttft.Seconds(),
+		Tpot: slo.Tpot.Seconds(),
+		E2e:  slo.E2e.Seconds(),
+	}
 }
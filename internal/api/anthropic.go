@@ -0,0 +1,216 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ProviderAnthropic speaks the Anthropic Messages API.
+const ProviderAnthropic = "anthropic"
+
+// DefaultAnthropicVersion is sent as the anthropic-version header unless another is given.
+const DefaultAnthropicVersion = "2023-06-01"
+
+func init() {
+	RegisterProvider(ProviderAnthropic, newAnthropicProvider)
+}
+
+// anthropicProvider talks to the Anthropic Messages API or a compatible
+// gateway. The endpoint's base URL includes the version path, e.g.
+// https://api.anthropic.com/v1, and its API version is the anthropic-version header.
+type anthropicProvider struct {
+	endpoint Endpoint
+}
+
+func newAnthropicProvider(endpoint Endpoint) (Provider, error) {
+	if endpoint.ApiVersion == "" {
+		endpoint.ApiVersion = DefaultAnthropicVersion
+	}
+	return &anthropicProvider{endpoint: endpoint}, nil
+}
+
+// anthropicError is the error object of the Anthropic API, in an HTTP response
+// or in an error event of the stream.
+type anthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// anthropicEvent holds the fields of every streamed event type used here.
+type anthropicEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		Thinking   string `json:"thinking"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"`
+	Error *anthropicError `json:"error"`
+}
+
+func (provider *anthropicProvider) newRequest(method string, path string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(context.Background(), method, strings.TrimSuffix(provider.endpoint.BaseURL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("x-api-key", provider.endpoint.ApiKey)
+	request.Header.Set("anthropic-version", provider.endpoint.ApiVersion)
+	request.Header.Set("Content-Type", "application/json")
+	return request, nil
+}
+
+// Stream sends a prompt to the Anthropic Messages API, processes the response
+// stream and returns stats on it.
+func (provider *anthropicProvider) Stream(model string, prompt string, maxTokens int, progress func(tokens int)) (StreamStats, error) {
+	start := time.Now()
+
+	var (
+		stats           = StreamStats{EstimatedPromptTokens: EstimateTokens(prompt)}
+		firstTokenSeen  bool
+		usage           anthropicUsage
+		estimatedTokens int
+	)
+
+	body, err := json.Marshal(map[string]any{
+		"model":       model,
+		"max_tokens":  maxTokens,
+		"temperature": 1,
+		"stream":      true,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+	})
+	if err != nil {
+		return stats, fmt.Errorf("error marshalling request: %w", err)
+	}
+	request, err := provider.newRequest(http.MethodPost, "/messages", bytes.NewReader(body))
+	if err != nil {
+		return stats, fmt.Errorf("Anthropic API request failed: %w", err)
+	}
+	response, err := doRequest(provider.endpoint.httpClient(), request)
+	if err != nil {
+		return stats, fmt.Errorf("Anthropic API request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		// Event names are repeated in the data, so only data lines matter
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var event anthropicEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			stats.EstimatedCompletionTokens = estimatedTokens
+			return stats, fmt.Errorf("%w: invalid event: %w", ErrStream, err)
+		}
+		stats.ChunkCount++
+
+		switch event.Type {
+		case "message_start":
+			usage = event.Message.Usage
+			stats.UsageReported = true
+		case "content_block_delta":
+			content := event.Delta.Thinking + event.Delta.Text
+			if content == "" {
+				continue
+			}
+			if !firstTokenSeen {
+				stats.TimeToFirstToken = time.Since(start).Seconds()
+				firstTokenSeen = true
+			}
+			stats.ChunkTimes = append(stats.ChunkTimes, time.Since(start).Seconds())
+			newTokens := EstimateTokens(content)
+			estimatedTokens += newTokens
+			if progress != nil {
+				progress(newTokens)
+			}
+		case "message_delta":
+			if event.Delta.StopReason != "" {
+				stats.FinishReason = event.Delta.StopReason
+			}
+			if event.Usage != nil {
+				// Counts are cumulative, input counts are only present when they changed
+				usage.OutputTokens = event.Usage.OutputTokens
+				if event.Usage.InputTokens > 0 {
+					usage.InputTokens = event.Usage.InputTokens
+				}
+				stats.UsageReported = true
+			}
+		case "error":
+			stats.EstimatedCompletionTokens = estimatedTokens
+			streamErr := &StatusError{Type: "error", Message: "unknown stream error"}
+			if event.Error != nil {
+				streamErr.Type, streamErr.Message = event.Error.Type, event.Error.Message
+			}
+			return stats, fmt.Errorf("%w: %w", ErrStream, streamErr)
+		}
+	}
+	stats.EstimatedCompletionTokens = estimatedTokens
+	if err := scanner.Err(); err != nil {
+		return stats, fmt.Errorf("%w: %w", ErrStream, err)
+	}
+
+	if stats.UsageReported {
+		// Cached prompt tokens are reported apart from input_tokens, but were part of the prompt
+		stats.PromptTokens = usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+		stats.CompletionTokens = usage.OutputTokens
+		if progress != nil && stats.CompletionTokens > 0 {
+			if diff := stats.CompletionTokens - estimatedTokens; diff != 0 {
+				progress(diff)
+			}
+		}
+	} else {
+		stats.PromptTokens = stats.EstimatedPromptTokens
+		stats.CompletionTokens = estimatedTokens
+	}
+
+	return stats, nil
+}
+
+// Models lists the models of the Anthropic API.
+func (provider *anthropicProvider) Models() ([]string, error) {
+	request, err := provider.newRequest(http.MethodGet, "/models?limit=1000", nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := doRequest(provider.endpoint.httpClient(), request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+	var models []string
+	for _, model := range list.Data {
+		models = append(models, model.ID)
+	}
+	return models, nil
+}
@@ -5,26 +5,79 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/sashabaranov/go-openai"
-	"github.com/schollz/progressbar/v3"
 )
 
-// AskOpenAi sends a prompt to the OpenAI API, processes the response stream and returns stats on it.
-func AskOpenAi(client *openai.Client, model string, prompt string, maxTokens int, bar *progressbar.ProgressBar) (float64, int, int, error) {
+// ProviderOpenAI speaks the OpenAI chat completions protocol, the default.
+const ProviderOpenAI = "openai"
+
+func init() {
+	RegisterProvider(ProviderOpenAI, newOpenAiProvider)
+}
+
+// StreamStats holds what was observed while streaming a single response.
+type StreamStats struct {
+	TimeToFirstToken float64   // Seconds from sending the request to the first token
+	ChunkTimes       []float64 // Seconds from sending the request to each chunk carrying content
+	CompletionTokens int
+	PromptTokens     int
+
+	UsageReported             bool // Whether the server reported usage, otherwise the token counts are estimates
+	EstimatedPromptTokens     int
+	EstimatedCompletionTokens int
+	FinishReason              string
+	ChunkCount                int // Stream chunks received, with or without content
+
+	// Server's own timings, nil unless the provider's server reports them
+	Server *ServerTimings
+}
+
+// ServerTimings is the server's account of a request's prefill and decode,
+// without network or queueing time.
+type ServerTimings struct {
+	PromptTokens   int // Prompt tokens the server evaluated
+	PromptDuration time.Duration
+	DecodeTokens   int
+	DecodeDuration time.Duration
+
+	CacheReported bool // Whether the server reports prompt cache hits
+	CachedTokens  int  // Prompt tokens reused from the server's cache, not part of PromptTokens
+}
+
+// ErrStream marks errors that happened after the response stream started.
+var ErrStream = errors.New("stream error")
+
+// openAiProvider streams chat completions through go-openai.
+type openAiProvider struct {
+	client *openai.Client
+}
+
+func newOpenAiProvider(endpoint Endpoint) (Provider, error) {
+	config := openai.DefaultConfig(endpoint.ApiKey)
+	config.BaseURL = endpoint.BaseURL
+	config.APIVersion = endpoint.ApiVersion
+	config.HTTPClient = endpoint.httpClient()
+	return &openAiProvider{client: openai.NewClientWithConfig(config)}, nil
+}
+
+// Stream sends a prompt to the OpenAI API, processes the response stream and returns stats on it.
+func (provider *openAiProvider) Stream(model string, prompt string, maxTokens int, progress func(tokens int)) (StreamStats, error) {
 	start := time.Now()
 
 	var (
-		timeToFirstToken   float64
+		stats              = StreamStats{EstimatedPromptTokens: EstimateTokens(prompt)}
 		firstTokenSeen     bool
 		lastUsage          *openai.Usage
 		accumulatedContent string // Accumulate all content to count tokens more accurately
 		estimatedTokens    int    // Real-time token estimation
 	)
 
-	stream, err := client.CreateChatCompletionStream(
+	stream, err := provider.client.CreateChatCompletionStream(
 		context.Background(),
 		openai.ChatCompletionRequest{
 			Model: model,
@@ -45,7 +98,7 @@ func AskOpenAi(client *openai.Client, model string, prompt string, maxTokens int
 		},
 	)
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("OpenAI API request failed: %w", err)
+		return stats, fmt.Errorf("OpenAI API request failed: %w", err)
 	}
 	defer stream.Close()
 
@@ -55,14 +108,19 @@ func AskOpenAi(client *openai.Client, model string, prompt string, maxTokens int
 			break
 		}
 		if err != nil {
-			return 0, 0, 0, fmt.Errorf("stream error: %w", err)
+			stats.EstimatedCompletionTokens = estimatedTokens
+			return stats, fmt.Errorf("%w: %w", ErrStream, err)
+		}
+		stats.ChunkCount++
+		if len(resp.Choices) > 0 && resp.Choices[0].FinishReason != "" {
+			stats.FinishReason = string(resp.Choices[0].FinishReason)
 		}
 
 		if !firstTokenSeen && len(resp.Choices) > 0 {
 			delta := resp.Choices[0].Delta
 			// Capture TTFT on the first chunk that has either regular content, reasoning content, or a finish reason
 			if delta.Content != "" || delta.ReasoningContent != "" || resp.Choices[0].FinishReason != "" {
-				timeToFirstToken = time.Since(start).Seconds()
+				stats.TimeToFirstToken = time.Since(start).Seconds()
 				firstTokenSeen = true
 			}
 		}
@@ -73,14 +131,15 @@ func AskOpenAi(client *openai.Client, model string, prompt string, maxTokens int
 			// Both reasoning content and regular content should be processed for the progress bar
 			content := delta.ReasoningContent + delta.Content
 			if content != "" {
+				stats.ChunkTimes = append(stats.ChunkTimes, time.Since(start).Seconds())
 				accumulatedContent += content
 
 				// Estimate number of tokens in current chunk
-				newTokens := estimateTokens(content)
+				newTokens := EstimateTokens(content)
 				estimatedTokens += newTokens
 
-				if bar != nil {
-					bar.Add(newTokens)
+				if progress != nil {
+					progress(newTokens)
 				}
 			}
 		}
@@ -90,32 +149,65 @@ func AskOpenAi(client *openai.Client, model string, prompt string, maxTokens int
 		}
 	}
 
-	var promptTokens, completionTokens int
+	stats.EstimatedCompletionTokens = estimatedTokens
 	if lastUsage != nil {
-		promptTokens = lastUsage.PromptTokens
-		completionTokens = lastUsage.CompletionTokens
+		stats.UsageReported = true
+		stats.PromptTokens = lastUsage.PromptTokens
+		stats.CompletionTokens = lastUsage.CompletionTokens
 
 		// Final adjustment: if we have actual completion tokens, adjust the progress bar
-		if bar != nil && completionTokens > 0 {
-			diff := completionTokens - estimatedTokens
+		if progress != nil && stats.CompletionTokens > 0 {
+			diff := stats.CompletionTokens - estimatedTokens
 			if diff != 0 { // Could be positive or negative
-				bar.Add(diff)
+				progress(diff)
 			}
 		}
 	} else {
-		// If no usage info, use our estimated tokens as completion tokens
-		completionTokens = estimatedTokens
+		// If no usage info, use our estimates as the token counts
+		stats.PromptTokens = stats.EstimatedPromptTokens
+		stats.CompletionTokens = estimatedTokens
 	}
 
-	return timeToFirstToken, completionTokens, promptTokens, nil
+	return stats, nil
 }
 
-func AskOpenAiRandomInput(client *openai.Client, model string, numWords int, maxTokens int, bar *progressbar.ProgressBar) (float64, int, int, error) {
-	prompt := generateRandomPhrase(numWords)
-	return AskOpenAi(client, model, prompt, maxTokens, bar)
+// ClassifyError sorts a request error into a coarse class for reporting, and
+// returns the HTTP status code if the server answered with one.
+func ClassifyError(err error) (int, string) {
+	status := 0
+	var apiErr *openai.APIError
+	var requestErr *openai.RequestError
+	var statusErr *StatusError
+	if errors.As(err, &apiErr) {
+		status = apiErr.HTTPStatusCode
+	} else if errors.As(err, &requestErr) {
+		status = requestErr.HTTPStatusCode
+	} else if errors.As(err, &statusErr) {
+		status = statusErr.HTTPStatusCode
+	}
+
+	var netErr net.Error
+	switch {
+	case status == http.StatusTooManyRequests:
+		return status, "rate_limited"
+	case status >= 500:
+		return status, "server_error"
+	case status >= 400:
+		return status, "client_error"
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return status, "timeout"
+	case errors.Is(err, ErrStream):
+		return status, "stream"
+	case errors.As(err, &netErr):
+		return status, "connection"
+	default:
+		return status, "other"
+	}
 }
 
-func estimateTokens(content string) int {
+// EstimateTokens approximates the number of tokens in content for servers that
+// don't report usage.
+func EstimateTokens(content string) int {
 	if content == "" {
 		return 0
 	}
@@ -141,16 +233,16 @@ func estimateTokens(content string) int {
 	}
 }
 
-// GetFirstAvailableModel retrieves the first available model from the OpenAI API.
-func GetFirstAvailableModel(client *openai.Client) (string, error) {
-	modelList, err := client.ListModels(context.Background())
+// Models lists the models of the OpenAI API.
+func (provider *openAiProvider) Models() ([]string, error) {
+	modelList, err := provider.client.ListModels(context.Background())
 	if err != nil {
-		return "", fmt.Errorf("failed to list models: %w", err)
+		return nil, err
 	}
 
-	if len(modelList.Models) == 0 {
-		return "", fmt.Errorf("no models available")
+	var models []string
+	for _, model := range modelList.Models {
+		models = append(models, model.ID)
 	}
-
-	return modelList.Models[0].ID, nil
+	return models, nil
 }
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ProviderLlamaCpp speaks the native /completion API of llama.cpp's server.
+const ProviderLlamaCpp = "llamacpp"
+
+func init() {
+	RegisterProvider(ProviderLlamaCpp, func(endpoint Endpoint) (Provider, error) {
+		return &llamaCppProvider{endpoint: endpoint}, nil
+	})
+}
+
+// llamaCppProvider talks to llama-server. The endpoint's base URL is the server
+// root, e.g. http://localhost:8080.
+type llamaCppProvider struct {
+	endpoint Endpoint
+}
+
+// llamaCppTimings is the timings object of llama-server's final chunk. Its
+// prompt_per_second and predicted_per_second follow from the counts and times.
+type llamaCppTimings struct {
+	CacheN      int     `json:"cache_n"`
+	PromptN     int     `json:"prompt_n"`
+	PromptMs    float64 `json:"prompt_ms"`
+	PredictedN  int     `json:"predicted_n"`
+	PredictedMs float64 `json:"predicted_ms"`
+}
+
+// llamaCppChunk is an event of a streamed /completion response.
+type llamaCppChunk struct {
+	Content         string           `json:"content"`
+	Stop            bool             `json:"stop"`
+	StopType        string           `json:"stop_type"`
+	TokensEvaluated int              `json:"tokens_evaluated"`
+	TokensPredicted int              `json:"tokens_predicted"`
+	Timings         *llamaCppTimings `json:"timings"`
+	Error           *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (provider *llamaCppProvider) newRequest(method string, path string, body []byte) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(context.Background(), method, strings.TrimSuffix(provider.endpoint.BaseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if provider.endpoint.ApiKey != "" {
+		request.Header.Set("Authorization", "Bearer "+provider.endpoint.ApiKey)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	return request, nil
+}
+
+// Stream sends a prompt to llama-server's /completion, processes the response
+// stream and returns stats on it, including the server's timings.
+func (provider *llamaCppProvider) Stream(model string, prompt string, maxTokens int, progress func(tokens int)) (StreamStats, error) {
+	start := time.Now()
+
+	var (
+		stats           = StreamStats{EstimatedPromptTokens: EstimateTokens(prompt)}
+		firstTokenSeen  bool
+		estimatedTokens int
+	)
+
+	// llama-server serves a single model, which it ignores here unless it routes between several
+	body, err := json.Marshal(map[string]any{
+		"model":        model,
+		"prompt":       prompt,
+		"n_predict":    maxTokens,
+		"temperature":  1,
+		"stream":       true,
+		"cache_prompt": true,
+	})
+	if err != nil {
+		return stats, fmt.Errorf("error marshalling request: %w", err)
+	}
+	request, err := provider.newRequest(http.MethodPost, "/completion", body)
+	if err != nil {
+		return stats, fmt.Errorf("llama.cpp API request failed: %w", err)
+	}
+	response, err := doRequest(provider.endpoint.httpClient(), request)
+	if err != nil {
+		return stats, fmt.Errorf("llama.cpp API request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	var final *llamaCppChunk
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var chunk llamaCppChunk
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &chunk); err != nil {
+			stats.EstimatedCompletionTokens = estimatedTokens
+			return stats, fmt.Errorf("%w: invalid chunk: %w", ErrStream, err)
+		}
+		stats.ChunkCount++
+		if chunk.Error != nil {
+			stats.EstimatedCompletionTokens = estimatedTokens
+			return stats, fmt.Errorf("%w: %w", ErrStream, &StatusError{Type: chunk.Error.Type, Message: chunk.Error.Message})
+		}
+
+		if chunk.Content != "" {
+			if !firstTokenSeen {
+				stats.TimeToFirstToken = time.Since(start).Seconds()
+				firstTokenSeen = true
+			}
+			stats.ChunkTimes = append(stats.ChunkTimes, time.Since(start).Seconds())
+			newTokens := EstimateTokens(chunk.Content)
+			estimatedTokens += newTokens
+			if progress != nil {
+				progress(newTokens)
+			}
+		}
+		if chunk.Stop {
+			final = &chunk
+			break
+		}
+	}
+	stats.EstimatedCompletionTokens = estimatedTokens
+	if err := scanner.Err(); err != nil {
+		return stats, fmt.Errorf("%w: %w", ErrStream, err)
+	}
+	if final == nil {
+		return stats, fmt.Errorf("%w: stream ended before the final chunk", ErrStream)
+	}
+
+	stats.FinishReason = final.StopType
+	stats.UsageReported = true
+	// tokens_evaluated counts the whole prompt, cached or not
+	stats.PromptTokens = final.TokensEvaluated
+	stats.CompletionTokens = final.TokensPredicted
+	if timings := final.Timings; timings != nil {
+		stats.Server = &ServerTimings{
+			PromptTokens:   timings.PromptN,
+			PromptDuration: time.Duration(timings.PromptMs * float64(time.Millisecond)),
+			DecodeTokens:   timings.PredictedN,
+			DecodeDuration: time.Duration(timings.PredictedMs * float64(time.Millisecond)),
+			CacheReported:  true,
+			CachedTokens:   timings.CacheN,
+		}
+	}
+	if progress != nil && stats.CompletionTokens > 0 {
+		if diff := stats.CompletionTokens - estimatedTokens; diff != 0 {
+			progress(diff)
+		}
+	}
+
+	return stats, nil
+}
+
+// Models lists the models llama-server serves, from its OpenAI-compatible listing.
+func (provider *llamaCppProvider) Models() ([]string, error) {
+	request, err := provider.newRequest(http.MethodGet, "/v1/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := doRequest(provider.endpoint.httpClient(), request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+	var models []string
+	for _, model := range list.Data {
+		models = append(models, model.ID)
+	}
+	return models, nil
+}
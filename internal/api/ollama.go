@@ -0,0 +1,194 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Ollama's native protocol, chatting through /api/chat or completing a raw
+// prompt through /api/generate.
+const (
+	ProviderOllama         = "ollama"
+	ProviderOllamaGenerate = "ollama-generate"
+)
+
+func init() {
+	RegisterProvider(ProviderOllama, func(endpoint Endpoint) (Provider, error) {
+		return &ollamaProvider{endpoint: endpoint}, nil
+	})
+	RegisterProvider(ProviderOllamaGenerate, func(endpoint Endpoint) (Provider, error) {
+		return &ollamaProvider{endpoint: endpoint, generate: true}, nil
+	})
+}
+
+// ollamaProvider talks to Ollama's native API. The endpoint's base URL is the
+// server root, e.g. http://localhost:11434.
+type ollamaProvider struct {
+	endpoint Endpoint
+	generate bool // Use /api/generate instead of /api/chat
+}
+
+// ollamaChunk is a line of a streamed /api/chat or /api/generate response.
+// Durations are in nanoseconds and only set on the final line.
+type ollamaChunk struct {
+	Message struct {
+		Content  string `json:"content"`
+		Thinking string `json:"thinking"`
+	} `json:"message"`
+	Response           string `json:"response"`
+	Thinking           string `json:"thinking"`
+	Done               bool   `json:"done"`
+	DoneReason         string `json:"done_reason"`
+	PromptEvalCount    int    `json:"prompt_eval_count"`
+	PromptEvalDuration int64  `json:"prompt_eval_duration"`
+	EvalCount          int    `json:"eval_count"`
+	EvalDuration       int64  `json:"eval_duration"`
+	Error              string `json:"error"`
+}
+
+func (provider *ollamaProvider) newRequest(method string, path string, body []byte) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(context.Background(), method, strings.TrimSuffix(provider.endpoint.BaseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if provider.endpoint.ApiKey != "" {
+		// Ollama itself has no authentication, but proxies in front of it may
+		request.Header.Set("Authorization", "Bearer "+provider.endpoint.ApiKey)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	return request, nil
+}
+
+// Stream sends a prompt to Ollama, processes the response stream and returns
+// stats on it, including the prefill and decode timings Ollama reports.
+func (provider *ollamaProvider) Stream(model string, prompt string, maxTokens int, progress func(tokens int)) (StreamStats, error) {
+	start := time.Now()
+
+	var (
+		stats           = StreamStats{EstimatedPromptTokens: EstimateTokens(prompt)}
+		firstTokenSeen  bool
+		estimatedTokens int
+	)
+
+	path := "/api/chat"
+	request := map[string]any{
+		"model":   model,
+		"stream":  true,
+		"options": map[string]any{"num_predict": maxTokens, "temperature": 1},
+	}
+	if provider.generate {
+		path = "/api/generate"
+		request["prompt"] = prompt
+	} else {
+		request["messages"] = []map[string]string{{"role": "user", "content": prompt}}
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return stats, fmt.Errorf("error marshalling request: %w", err)
+	}
+	httpRequest, err := provider.newRequest(http.MethodPost, path, body)
+	if err != nil {
+		return stats, fmt.Errorf("Ollama API request failed: %w", err)
+	}
+	response, err := doRequest(provider.endpoint.httpClient(), httpRequest)
+	if err != nil {
+		return stats, fmt.Errorf("Ollama API request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	var final *ollamaChunk
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			stats.EstimatedCompletionTokens = estimatedTokens
+			return stats, fmt.Errorf("%w: invalid chunk: %w", ErrStream, err)
+		}
+		stats.ChunkCount++
+		if chunk.Error != "" {
+			stats.EstimatedCompletionTokens = estimatedTokens
+			return stats, fmt.Errorf("%w: %w", ErrStream, &StatusError{Type: "error", Message: chunk.Error})
+		}
+
+		content := chunk.Message.Thinking + chunk.Message.Content + chunk.Thinking + chunk.Response
+		if content != "" {
+			if !firstTokenSeen {
+				stats.TimeToFirstToken = time.Since(start).Seconds()
+				firstTokenSeen = true
+			}
+			stats.ChunkTimes = append(stats.ChunkTimes, time.Since(start).Seconds())
+			newTokens := EstimateTokens(content)
+			estimatedTokens += newTokens
+			if progress != nil {
+				progress(newTokens)
+			}
+		}
+		if chunk.Done {
+			final = &chunk
+			break
+		}
+	}
+	stats.EstimatedCompletionTokens = estimatedTokens
+	if err := scanner.Err(); err != nil {
+		return stats, fmt.Errorf("%w: %w", ErrStream, err)
+	}
+	if final == nil {
+		return stats, fmt.Errorf("%w: stream ended before the final chunk", ErrStream)
+	}
+
+	stats.FinishReason = final.DoneReason
+	stats.UsageReported = true
+	stats.PromptTokens = final.PromptEvalCount
+	stats.CompletionTokens = final.EvalCount
+	stats.Server = &ServerTimings{
+		PromptTokens:   final.PromptEvalCount,
+		PromptDuration: time.Duration(final.PromptEvalDuration),
+		DecodeTokens:   final.EvalCount,
+		DecodeDuration: time.Duration(final.EvalDuration),
+	}
+	if progress != nil && stats.CompletionTokens > 0 {
+		if diff := stats.CompletionTokens - estimatedTokens; diff != 0 {
+			progress(diff)
+		}
+	}
+
+	return stats, nil
+}
+
+// Models lists the models pulled on the Ollama server.
+func (provider *ollamaProvider) Models() ([]string, error) {
+	request, err := provider.newRequest(http.MethodGet, "/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := doRequest(provider.endpoint.httpClient(), request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&tags); err != nil {
+		return nil, err
+	}
+	var models []string
+	for _, model := range tags.Models {
+		models = append(models, model.Name)
+	}
+	return models, nil
+}
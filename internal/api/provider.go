@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Provider streams completions from an endpoint speaking one API protocol.
+// Implementations must be safe for concurrent use.
+type Provider interface {
+	// Stream sends prompt to model, consumes the streamed response and returns
+	// the chunk timings, usage and finish reason observed. Progress, if not nil,
+	// is called with the estimated tokens of each content chunk as it arrives,
+	// and with a correction once the server reports usage. Errors that happened
+	// after the stream started wrap ErrStream, errors carrying an HTTP status
+	// should wrap a *StatusError so ClassifyError can sort them.
+	Stream(model string, prompt string, maxTokens int, progress func(tokens int)) (StreamStats, error)
+
+	// Models returns the IDs of the models the endpoint serves.
+	Models() ([]string, error)
+}
+
+// Endpoint holds what a provider needs to connect to a server.
+type Endpoint struct {
+	BaseURL    string
+	ApiKey     string
+	ApiVersion string       // Protocol specific, e.g. Azure's api-version or the anthropic-version header
+	HTTPClient *http.Client // http.DefaultClient if nil
+}
+
+func (endpoint Endpoint) httpClient() *http.Client {
+	if endpoint.HTTPClient != nil {
+		return endpoint.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// ProviderFactory creates a provider for an endpoint.
+type ProviderFactory func(endpoint Endpoint) (Provider, error)
+
+var providers = map[string]ProviderFactory{}
+
+// RegisterProvider makes a provider available under name, as selected by --provider.
+func RegisterProvider(name string, factory ProviderFactory) {
+	if _, ok := providers[name]; ok {
+		panic("provider registered twice: " + name)
+	}
+	providers[name] = factory
+}
+
+// ProviderNames returns the names of the registered providers, sorted.
+func ProviderNames() []string {
+	var names []string
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProvider returns the provider registered under name for endpoint.
+func NewProvider(name string, endpoint Endpoint) (Provider, error) {
+	factory, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q, available: %s", name, strings.Join(ProviderNames(), ", "))
+	}
+	return factory(endpoint)
+}
+
+// StatusError is an error response of a server, for providers without a client
+// library of their own.
+type StatusError struct {
+	HTTPStatusCode int // 0 for errors reported inside the stream
+	Type           string
+	Message        string
+}
+
+func (err *StatusError) Error() string {
+	if err.HTTPStatusCode == 0 {
+		return fmt.Sprintf("%s: %s", err.Type, err.Message)
+	}
+	return fmt.Sprintf("status code: %d, %s: %s", err.HTTPStatusCode, err.Type, err.Message)
+}
+
+// doRequest sends request and returns the response, or a *StatusError with the
+// server's message if the status isn't 200.
+func doRequest(client *http.Client, request *http.Request) (*http.Response, error) {
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusOK {
+		return response, nil
+	}
+	defer response.Body.Close()
+
+	statusErr := &StatusError{HTTPStatusCode: response.StatusCode, Type: "error", Message: response.Status}
+	// Servers put either an object or just a message under "error"
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	data, err := io.ReadAll(response.Body)
+	if err != nil || json.Unmarshal(data, &body) != nil || body.Error == nil {
+		return nil, statusErr
+	}
+	var object struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body.Error, &object) == nil {
+		if object.Type != "" {
+			statusErr.Type = object.Type
+		}
+		if object.Message != "" {
+			statusErr.Message = object.Message
+		}
+	} else {
+		json.Unmarshal(body.Error, &statusErr.Message)
+	}
+	return nil, statusErr
+}
+
+// GetFirstAvailableModel retrieves the first model the provider's endpoint serves.
+func GetFirstAvailableModel(provider Provider) (string, error) {
+	models, err := provider.Models()
+	if err != nil {
+		return "", fmt.Errorf("failed to list models: %w", err)
+	}
+
+	if len(models) == 0 {
+		return "", fmt.Errorf("no models available")
+	}
+
+	return models[0], nil
+}
+
+// GetModelsMatching returns the IDs of the available models matching pattern.
+func GetModelsMatching(provider Provider, pattern *regexp.Regexp) ([]string, error) {
+	available, err := provider.Models()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+
+	var models []string
+	for _, model := range available {
+		if pattern.MatchString(model) {
+			models = append(models, model)
+		}
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no models match %s", pattern)
+	}
+
+	return models, nil
+}
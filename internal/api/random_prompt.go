@@ -14,26 +14,29 @@ const (
 var letters = []rune("abcdefghijklmnopqrstuvwxyz")
 
 // generateRandomWord
-func generateRandomWord() string {
+func generateRandomWord(rng *rand.Rand) string {
 	// length（3-10）
-	wordLength := minWordLength + rand.Intn(maxWordLength-minWordLength+1)
+	wordLength := minWordLength + rng.Intn(maxWordLength-minWordLength+1)
 
 	word := make([]rune, wordLength)
 
 	for i := 0; i < wordLength; i++ {
-		word[i] = letters[rand.Intn(len(letters))]
+		word[i] = letters[rng.Intn(len(letters))]
 	}
 
 	return string(word)
 }
 
-// generateRandomPhrase
-func generateRandomPhrase(numWords int) string {
-	rand.Seed(time.Now().UnixNano())
+// GenerateRandomPhrase builds a prompt asking to echo numWords random words.
+func GenerateRandomPhrase(numWords int) string {
+	return RandomPhrase(rand.New(rand.NewSource(time.Now().UnixNano())), numWords)
+}
 
+// RandomPhrase builds a prompt asking to echo numWords random words drawn from rng.
+func RandomPhrase(rng *rand.Rand, numWords int) string {
 	randomWords := make([]string, numWords)
 	for i := 0; i < numWords; i++ {
-		randomWords[i] = generateRandomWord()
+		randomWords[i] = generateRandomWord(rng)
 	}
 
 	randomPhrase := strings.Join(randomWords, " ")
@@ -0,0 +1,509 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultMaxTokens is generated when a request doesn't set a limit.
+const defaultMaxTokens = 256
+
+// word is streamed once per token; it estimates to exactly one token on the client.
+const word = "lorem "
+
+// Config describes the behaviour of the mock server.
+type Config struct {
+	Model           string
+	Ttft            time.Duration // Time to first token once a slot is free
+	TokensPerSecond float64       // Decode speed of each request
+	Jitter          float64       // Relative per-request jitter applied to Ttft and TokensPerSecond, 0-1
+	Slots           int           // Requests generated at once, excess requests queue; 0 is unlimited
+	ErrorRate       float64       // Fraction of requests failing with HTTP 500, 0-1
+	OmitUsage       bool          // Never report usage, even when the client asks for it
+}
+
+// Server is an OpenAI-compatible HTTP handler streaming synthetic completions
+// with known timings. It also serves the Anthropic Messages API, Ollama's
+// native API and llama.cpp's /completion.
+// Anthropic responses always carry usage, as the real API does.
+type Server struct {
+	config Config
+	slots  chan struct{}
+	mux    *http.ServeMux
+}
+
+// NewServer returns a mock server for config.
+func NewServer(config Config) *Server {
+	server := &Server{config: config, mux: http.NewServeMux()}
+	if config.Slots > 0 {
+		server.slots = make(chan struct{}, config.Slots)
+	}
+	server.mux.HandleFunc("GET /v1/models", server.handleModels)
+	server.mux.HandleFunc("POST /v1/chat/completions", server.handleChatCompletions)
+	server.mux.HandleFunc("POST /v1/messages", server.handleMessages)
+	server.mux.HandleFunc("GET /api/tags", server.handleOllamaTags)
+	server.mux.HandleFunc("POST /api/chat", server.handleOllama)
+	server.mux.HandleFunc("POST /api/generate", server.handleOllama)
+	server.mux.HandleFunc("POST /completion", server.handleLlamaCpp)
+	return server
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mux.ServeHTTP(w, r)
+}
+
+func (server *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"data": []map[string]any{
+			{"id": server.config.Model, "object": "model", "owned_by": "mock"},
+		},
+	})
+}
+
+type chatCompletionRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Content string `json:"content"`
+	} `json:"messages"`
+	MaxTokens           int  `json:"max_tokens"`
+	MaxCompletionTokens int  `json:"max_completion_tokens"`
+	Stream              bool `json:"stream"`
+	StreamOptions       *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+func (server *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var request chatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	promptTokens := 0
+	for _, message := range request.Messages {
+		promptTokens += len(strings.Fields(message.Content))
+	}
+	maxTokens := request.MaxCompletionTokens
+	if maxTokens == 0 {
+		maxTokens = request.MaxTokens
+	}
+	if maxTokens == 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	generation, ok := server.generate(w, r, maxTokens)
+	if !ok {
+		return
+	}
+	defer generation.release()
+
+	id := fmt.Sprintf("chatcmpl-mock-%d", time.Now().UnixNano())
+	created := time.Now().Unix()
+	requestUsage := usage{promptTokens, maxTokens, promptTokens + maxTokens}
+
+	if !request.Stream {
+		if !generation.waitToken(r, maxTokens-1) {
+			return
+		}
+		response := map[string]any{
+			"id": id, "object": "chat.completion", "created": created, "model": request.Model,
+			"choices": []map[string]any{{
+				"index":         0,
+				"message":       map[string]any{"role": "assistant", "content": strings.Repeat(word, maxTokens)},
+				"finish_reason": "length",
+			}},
+		}
+		if !server.config.OmitUsage {
+			response["usage"] = requestUsage
+		}
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	chunk := func(choices []map[string]any, usage *usage) map[string]any {
+		data := map[string]any{"id": id, "object": "chat.completion.chunk", "created": created, "model": request.Model, "choices": choices}
+		if usage != nil {
+			data["usage"] = usage
+		}
+		return data
+	}
+
+	stream := newEventStream(w)
+	for i := 0; i < maxTokens; i++ {
+		if !generation.waitToken(r, i) {
+			return
+		}
+		delta := map[string]any{"content": word}
+		if i == 0 {
+			delta["role"] = "assistant"
+		}
+		stream.data(chunk([]map[string]any{{"index": 0, "delta": delta}}, nil))
+	}
+	stream.data(chunk([]map[string]any{{"index": 0, "delta": map[string]any{}, "finish_reason": "length"}}, nil))
+	if request.StreamOptions != nil && request.StreamOptions.IncludeUsage && !server.config.OmitUsage {
+		stream.data(chunk([]map[string]any{}, &requestUsage))
+	}
+	stream.done()
+}
+
+type messagesRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Content json.RawMessage `json:"content"` // A string or a list of content blocks
+	} `json:"messages"`
+	MaxTokens int  `json:"max_tokens"`
+	Stream    bool `json:"stream"`
+}
+
+// handleMessages serves the Anthropic Messages API.
+func (server *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	var request messagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	promptTokens := 0
+	for _, message := range request.Messages {
+		var text string
+		if json.Unmarshal(message.Content, &text) != nil {
+			var blocks []struct {
+				Text string `json:"text"`
+			}
+			json.Unmarshal(message.Content, &blocks)
+			for _, block := range blocks {
+				text += block.Text + " "
+			}
+		}
+		promptTokens += len(strings.Fields(text))
+	}
+	maxTokens := request.MaxTokens
+	if maxTokens == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "max_tokens: Field required")
+		return
+	}
+
+	generation, ok := server.generate(w, r, maxTokens)
+	if !ok {
+		return
+	}
+	defer generation.release()
+
+	id := fmt.Sprintf("msg_mock_%d", time.Now().UnixNano())
+	message := map[string]any{
+		"id": id, "type": "message", "role": "assistant", "model": request.Model,
+		"content": []map[string]any{}, "stop_reason": nil, "stop_sequence": nil,
+		"usage": map[string]any{"input_tokens": promptTokens, "output_tokens": 1},
+	}
+
+	if !request.Stream {
+		if !generation.waitToken(r, maxTokens-1) {
+			return
+		}
+		message["content"] = []map[string]any{{"type": "text", "text": strings.Repeat(word, maxTokens)}}
+		message["stop_reason"] = "max_tokens"
+		message["usage"] = map[string]any{"input_tokens": promptTokens, "output_tokens": maxTokens}
+		writeJSON(w, http.StatusOK, message)
+		return
+	}
+
+	stream := newEventStream(w)
+	stream.event("message_start", map[string]any{"type": "message_start", "message": message})
+	stream.event("content_block_start", map[string]any{"type": "content_block_start", "index": 0, "content_block": map[string]any{"type": "text", "text": ""}})
+	stream.event("ping", map[string]any{"type": "ping"})
+	for i := 0; i < maxTokens; i++ {
+		if !generation.waitToken(r, i) {
+			return
+		}
+		stream.event("content_block_delta", map[string]any{"type": "content_block_delta", "index": 0, "delta": map[string]any{"type": "text_delta", "text": word}})
+	}
+	stream.event("content_block_stop", map[string]any{"type": "content_block_stop", "index": 0})
+	stream.event("message_delta", map[string]any{
+		"type":  "message_delta",
+		"delta": map[string]any{"stop_reason": "max_tokens", "stop_sequence": nil},
+		"usage": map[string]any{"output_tokens": maxTokens},
+	})
+	stream.event("message_stop", map[string]any{"type": "message_stop"})
+}
+
+func (server *Server) handleOllamaTags(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"models": []map[string]any{{"name": server.config.Model, "model": server.config.Model}},
+	})
+}
+
+type ollamaRequest struct {
+	Model    string `json:"model"`
+	Prompt   string `json:"prompt"`
+	Messages []struct {
+		Content string `json:"content"`
+	} `json:"messages"`
+	Stream  *bool `json:"stream"` // Ollama streams unless told otherwise
+	Options struct {
+		NumPredict int `json:"num_predict"`
+	} `json:"options"`
+}
+
+// handleOllama serves Ollama's /api/chat and /api/generate. The final chunk
+// reports the prefill and decode durations the mock actually used.
+func (server *Server) handleOllama(w http.ResponseWriter, r *http.Request) {
+	var request ollamaRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	chat := r.URL.Path == "/api/chat"
+
+	promptTokens := len(strings.Fields(request.Prompt))
+	for _, message := range request.Messages {
+		promptTokens += len(strings.Fields(message.Content))
+	}
+	maxTokens := request.Options.NumPredict
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	generation, ok := server.generate(w, r, maxTokens)
+	if !ok {
+		return
+	}
+	defer generation.release()
+
+	chunk := func(content string, done bool) map[string]any {
+		data := map[string]any{"model": request.Model, "created_at": time.Now().UTC().Format(time.RFC3339Nano), "done": done}
+		if chat {
+			data["message"] = map[string]any{"role": "assistant", "content": content}
+		} else {
+			data["response"] = content
+		}
+		return data
+	}
+	final := func(content string) map[string]any {
+		data := chunk(content, true)
+		data["done_reason"] = "length"
+		data["prompt_eval_count"] = promptTokens
+		data["prompt_eval_duration"] = generation.ttft.Nanoseconds()
+		data["eval_count"] = maxTokens
+		data["eval_duration"] = (time.Duration(maxTokens) * generation.interval).Nanoseconds()
+		data["total_duration"] = time.Since(generation.start).Nanoseconds()
+		return data
+	}
+
+	if request.Stream != nil && !*request.Stream {
+		if !generation.waitToken(r, maxTokens-1) {
+			return
+		}
+		writeJSON(w, http.StatusOK, final(strings.Repeat(word, maxTokens)))
+		return
+	}
+
+	stream := newLineStream(w)
+	for i := 0; i < maxTokens; i++ {
+		if !generation.waitToken(r, i) {
+			return
+		}
+		stream.line(chunk(word, false))
+	}
+	stream.line(final(""))
+}
+
+type llamaCppRequest struct {
+	Prompt   string `json:"prompt"`
+	NPredict int    `json:"n_predict"`
+	Stream   bool   `json:"stream"`
+}
+
+// handleLlamaCpp serves llama.cpp's /completion. The final chunk reports the
+// prefill and decode times the mock actually used; it has no prompt cache.
+func (server *Server) handleLlamaCpp(w http.ResponseWriter, r *http.Request) {
+	var request llamaCppRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	promptTokens := len(strings.Fields(request.Prompt))
+	maxTokens := request.NPredict
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	generation, ok := server.generate(w, r, maxTokens)
+	if !ok {
+		return
+	}
+	defer generation.release()
+
+	final := func(content string) map[string]any {
+		promptMs := float64(generation.ttft) / float64(time.Millisecond)
+		predictedMs := float64(time.Duration(maxTokens)*generation.interval) / float64(time.Millisecond)
+		timings := map[string]any{
+			"cache_n":      0,
+			"prompt_n":     promptTokens,
+			"prompt_ms":    promptMs,
+			"predicted_n":  maxTokens,
+			"predicted_ms": predictedMs,
+		}
+		if promptMs > 0 {
+			timings["prompt_per_second"] = float64(promptTokens) / promptMs * 1000
+		}
+		if predictedMs > 0 {
+			timings["predicted_per_second"] = float64(maxTokens) / predictedMs * 1000
+		}
+		return map[string]any{
+			"content": content, "stop": true, "stop_type": "limit",
+			"tokens_evaluated": promptTokens, "tokens_predicted": maxTokens,
+			"timings": timings,
+		}
+	}
+
+	if !request.Stream {
+		if !generation.waitToken(r, maxTokens-1) {
+			return
+		}
+		writeJSON(w, http.StatusOK, final(strings.Repeat(word, maxTokens)))
+		return
+	}
+
+	stream := newEventStream(w)
+	for i := 0; i < maxTokens; i++ {
+		if !generation.waitToken(r, i) {
+			return
+		}
+		stream.data(map[string]any{"content": word, "stop": false})
+	}
+	stream.data(final(""))
+}
+
+// generation paces the tokens of a single request.
+type generation struct {
+	server   *Server
+	start    time.Time
+	ttft     time.Duration
+	interval time.Duration
+}
+
+// generate injects errors, waits for a free slot and samples this request's
+// jittered timings. It writes the error response and returns false when the
+// request must not be generated.
+func (server *Server) generate(w http.ResponseWriter, r *http.Request, maxTokens int) (*generation, bool) {
+	if rand.Float64() < server.config.ErrorRate {
+		writeError(w, http.StatusInternalServerError, "server_error", "mock injected error")
+		return nil, false
+	}
+
+	if server.slots != nil {
+		select {
+		case server.slots <- struct{}{}:
+		case <-r.Context().Done():
+			return nil, false
+		}
+	}
+
+	jitter := func() float64 { return 1 + server.config.Jitter*(2*rand.Float64()-1) }
+	interval := time.Duration(0)
+	if server.config.TokensPerSecond > 0 {
+		interval = time.Duration(float64(time.Second) / (server.config.TokensPerSecond * jitter()))
+	}
+	return &generation{
+		server:   server,
+		start:    time.Now(),
+		ttft:     time.Duration(float64(server.config.Ttft) * jitter()),
+		interval: interval,
+	}, true
+}
+
+func (generation *generation) release() {
+	if generation.server.slots != nil {
+		<-generation.server.slots
+	}
+}
+
+// waitToken sleeps until token i is due, scheduled against the start of the
+// generation so pacing doesn't drift. It returns false if the client went away.
+func (generation *generation) waitToken(r *http.Request, i int) bool {
+	due := generation.start.Add(generation.ttft + time.Duration(i)*generation.interval)
+	select {
+	case <-time.After(time.Until(due)):
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// eventStream writes server-sent events, or JSON lines.
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return &eventStream{w: w, flusher: flusher}
+}
+
+func (stream *eventStream) data(payload any) {
+	encoded, _ := json.Marshal(payload)
+	fmt.Fprintf(stream.w, "data: %s\n\n", encoded)
+	stream.flush()
+}
+
+// newLineStream starts a stream of newline-delimited JSON, as Ollama sends.
+func newLineStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return &eventStream{w: w, flusher: flusher}
+}
+
+// line writes payload as a single line of JSON.
+func (stream *eventStream) line(payload any) {
+	encoded, _ := json.Marshal(payload)
+	fmt.Fprintf(stream.w, "%s\n", encoded)
+	stream.flush()
+}
+
+// event writes a named event, as the Anthropic API does.
+func (stream *eventStream) event(name string, payload any) {
+	encoded, _ := json.Marshal(payload)
+	fmt.Fprintf(stream.w, "event: %s\ndata: %s\n\n", name, encoded)
+	stream.flush()
+}
+
+func (stream *eventStream) done() {
+	fmt.Fprint(stream.w, "data: [DONE]\n\n")
+	stream.flush()
+}
+
+func (stream *eventStream) flush() {
+	if stream.flusher != nil {
+		stream.flusher.Flush()
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+func writeError(w http.ResponseWriter, status int, errorType string, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{"message": message, "type": errorType},
+	})
+}
@@ -0,0 +1,96 @@
+package mock_test
+
+import (
+	"math"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/api"
+	"github.com/Yoosu-L/llmapibenchmark/internal/mock"
+	"github.com/Yoosu-L/llmapibenchmark/internal/utils"
+)
+
+const (
+	mockTtft            = 100 * time.Millisecond
+	mockTokensPerSecond = 50.0
+)
+
+// measure runs one level against a mock server through provider and returns its result.
+func measure(t *testing.T, provider string, path string) utils.SpeedResult {
+	t.Helper()
+	server := httptest.NewServer(mock.NewServer(mock.Config{
+		Model:           "mock-model",
+		Ttft:            mockTtft,
+		TokensPerSecond: mockTokensPerSecond,
+	}))
+	defer server.Close()
+
+	client, err := api.NewProvider(provider, api.Endpoint{BaseURL: server.URL + path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup := utils.SpeedMeasurement{
+		Client:      client,
+		ModelName:   "mock-model",
+		Prompt:      "Write a story.",
+		MaxTokens:   16,
+		Concurrency: 4,
+		Percentiles: []float64{95},
+	}
+	result, err := setup.Run(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SuccessRate != 1 {
+		t.Fatalf("success rate = %v, want 1", result.SuccessRate)
+	}
+	return result
+}
+
+// assertNear fails if got is off by more than tolerance, relative to want.
+func assertNear(t *testing.T, name string, got, want, tolerance float64) {
+	t.Helper()
+	if math.Abs(got-want) > want*tolerance {
+		t.Errorf("%s = %v, want %v ± %v%%", name, got, want, tolerance*100)
+	}
+}
+
+func TestMeasuredTimingsMatchMock(t *testing.T) {
+	for _, test := range []struct {
+		provider string
+		path     string
+		server   bool // The provider reports server-side timings
+	}{
+		{api.ProviderOpenAI, "/v1", false},
+		{api.ProviderAnthropic, "/v1", false},
+		{api.ProviderOllama, "", true},
+		{api.ProviderLlamaCpp, "", true},
+	} {
+		t.Run(test.provider, func(t *testing.T) {
+			result := measure(t, test.provider, test.path)
+
+			assertNear(t, "median TTFT", result.Ttft.Median, mockTtft.Seconds(), 0.2)
+			assertNear(t, "median TPOT", result.Tpot.Median, 1000/mockTokensPerSecond, 0.03)
+			assertNear(t, "median user speed", result.UserSpeed.Median, mockTokensPerSecond, 0.03)
+			if result.OutputLength.Median != 16 {
+				t.Errorf("median output length = %v, want 16", result.OutputLength.Median)
+			}
+
+			if !test.server {
+				return
+			}
+			if result.Server == nil {
+				t.Fatal("no server timings")
+			}
+			assertNear(t, "median server decode speed", result.Server.DecodeSpeed.Median, mockTokensPerSecond, 0.01)
+			// The client also waits for the network, so it cannot decode faster than the server
+			if result.UserSpeed.Median > result.Server.DecodeSpeed.Median*1.03 {
+				t.Errorf("median user speed %v exceeds server decode speed %v", result.UserSpeed.Median, result.Server.DecodeSpeed.Median)
+			}
+			if test.provider == api.ProviderLlamaCpp && (result.Server.CachedTokens == nil || result.Server.CachedTokens.Median != 0) {
+				t.Errorf("cached tokens = %v, want a median of 0", result.Server.CachedTokens)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	chartWidth = 40
+	histBins   = 32
+)
+
+// barEighths are the block characters drawing 1/8 to 8/8 of a cell.
+var barEighths = []string{"▏", "▎", "▍", "▌", "▋", "▊", "▉", "█"}
+
+// sparkLevels are the block characters drawing histogram bars of rising height.
+var sparkLevels = []string{"▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"}
+
+// BarChart renders one horizontal bar per label, scaled to the largest value.
+func BarChart(title string, labels []string, values []float64, unit string) string {
+	var sb strings.Builder
+	sb.WriteString(title + "\n")
+
+	labelWidth := maxWidth(labels)
+	largest := 0.0
+	for _, value := range values {
+		largest = math.Max(largest, value)
+	}
+	for i, value := range values {
+		eighths := 0
+		if largest > 0 {
+			eighths = int(math.Round(value / largest * chartWidth * 8))
+		}
+		bar := strings.Repeat(barEighths[7], eighths/8)
+		if eighths%8 > 0 {
+			bar += barEighths[eighths%8-1]
+		}
+		fmt.Fprintf(&sb, "%*s │%s%s %.2f%s\n", labelWidth, labels[i], bar,
+			strings.Repeat(" ", chartWidth-utf8.RuneCountInString(bar)), value, unit)
+	}
+	return sb.String()
+}
+
+// HistogramChart renders the distribution of each label's samples as a row of
+// bars over a range shared by all rows, so rows can be compared at a glance.
+func HistogramChart(title string, labels []string, samples [][]float64, unit string) string {
+	var sb strings.Builder
+	sb.WriteString(title + "\n")
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, values := range samples {
+		for _, value := range values {
+			low, high = math.Min(low, value), math.Max(high, value)
+		}
+	}
+	if math.IsInf(low, 1) {
+		sb.WriteString("(no successful requests)\n")
+		return sb.String()
+	}
+	span := high - low
+	if span == 0 {
+		span = 1
+	}
+
+	labelWidth := maxWidth(labels)
+	for i, values := range samples {
+		counts := make([]int, histBins)
+		peak := 0
+		for _, value := range values {
+			bin := min(histBins-1, int((value-low)/span*histBins))
+			counts[bin]++
+			peak = max(peak, counts[bin])
+		}
+		var row strings.Builder
+		for _, count := range counts {
+			if count == 0 {
+				row.WriteString(" ")
+				continue
+			}
+			level := (count*len(sparkLevels) - 1) / peak
+			row.WriteString(sparkLevels[level])
+		}
+		fmt.Fprintf(&sb, "%*s │%s│ n=%d\n", labelWidth, labels[i], row.String(), len(values))
+	}
+	lowLabel, highLabel := fmt.Sprintf("%.2f%s", low, unit), fmt.Sprintf("%.2f%s", high, unit)
+	fmt.Fprintf(&sb, "%*s  %s%*s\n", labelWidth, "", lowLabel, max(1, histBins-len(lowLabel)), highLabel)
+	return sb.String()
+}
+
+func maxWidth(labels []string) int {
+	width := 0
+	for _, label := range labels {
+		width = max(width, len(label))
+	}
+	return width
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"errors"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -30,4 +31,22 @@ func ParseConcurrencyLevels(concurrencyStr string) ([]int, error) {
 	return concurrencyLevels, nil
 }
 
+// ParseRates parses a comma-separated string of open-loop request rates (requests/sec).
+func ParseRates(ratesStr string) ([]float64, error) {
+	strRates := strings.Split(ratesStr, ",")
 
+	rates := make([]float64, 0, len(strRates))
+	for _, rateStr := range strRates {
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rateStr), 64)
+		if err != nil {
+			return nil, errors.New("invalid request rate: " + rateStr)
+		}
+		if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return nil, errors.New("request rate must be a positive number: " + rateStr)
+		}
+		rates = append(rates, rate)
+	}
+
+	sort.Float64s(rates)
+	return rates, nil
+}
@@ -0,0 +1,357 @@
+package utils
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportSeries is one benchmark drawn in the HTML report.
+type ReportSeries struct {
+	Name        string
+	ModelName   string
+	InputTokens int
+	MaxTokens   int
+	Latency     float64
+	OpenLoop    bool
+	Results     []SpeedResult
+}
+
+type htmlReport struct {
+	Generated string
+	Series    []htmlSeries
+}
+
+type htmlSeries struct {
+	Name        string      `json:"name"`
+	ModelName   string      `json:"model"`
+	InputTokens int         `json:"inputTokens"`
+	MaxTokens   int         `json:"maxTokens"`
+	Latency     float64     `json:"latency"`
+	OpenLoop    bool        `json:"openLoop"`
+	Levels      []htmlLevel `json:"levels"`
+}
+
+type htmlLevel struct {
+	Load        float64   `json:"load"` // Request rate of open-loop levels, concurrency otherwise
+	Concurrency int       `json:"concurrency"`
+	GenTps      float64   `json:"genTps"`
+	PromptTps   float64   `json:"promptTps"`
+	ReqPerSec   float64   `json:"reqPerSec"`
+	TtftP50     float64   `json:"ttftP50"`
+	TtftTail    float64   `json:"ttftTail"`
+	E2eP50      float64   `json:"e2eP50"`
+	E2eTail     float64   `json:"e2eTail"`
+	TailKey     string    `json:"tailKey"` // Percentile reported as the tail, e.g. "p95"
+	UserTps     float64   `json:"userTps"`
+	Success     float64   `json:"success"`
+	Ttfts       []float64 `json:"ttfts"`     // Per successful request, in seconds
+	Latencies   []float64 `json:"latencies"` // Per successful request, in seconds
+}
+
+// SaveResultsToHTML writes a self-contained HTML report to path, with charts of
+// throughput and TTFT against load, the latency/throughput trade-off and
+// per-request latency histograms. Data and scripts are inlined so the file
+// works offline.
+func SaveResultsToHTML(path string, series []ReportSeries) error {
+	report := htmlReport{Generated: time.Now().UTC().Format("2006-01-02 15:04:05 UTC+0")}
+	for _, s := range series {
+		converted := htmlSeries{
+			Name:        s.Name,
+			ModelName:   s.ModelName,
+			InputTokens: s.InputTokens,
+			MaxTokens:   s.MaxTokens,
+			Latency:     s.Latency,
+			OpenLoop:    s.OpenLoop,
+		}
+		for _, result := range s.Results {
+			level := htmlLevel{
+				Load:        float64(result.Concurrency),
+				Concurrency: result.Concurrency,
+				GenTps:      result.GenerationSpeed,
+				PromptTps:   result.PromptThroughput,
+				ReqPerSec:   result.RequestThroughput,
+				TtftP50:     result.Ttft.Median,
+				E2eP50:      result.E2eLatency.Median,
+				UserTps:     result.UserSpeed.Median,
+				Success:     result.SuccessRate,
+				Ttfts:       []float64{},
+				Latencies:   []float64{},
+			}
+			if s.OpenLoop {
+				level.Load = result.Rate
+			}
+			level.TailKey = tailPercentile(result.Ttft.Percentiles)
+			level.TtftTail = result.Ttft.Percentiles[level.TailKey]
+			level.E2eTail = result.E2eLatency.Percentiles[level.TailKey]
+			for _, request := range result.Requests {
+				if request.Error == "" {
+					level.Ttfts = append(level.Ttfts, math.Round(request.Ttft()*1e4)/1e4)
+					level.Latencies = append(level.Latencies, math.Round(request.Latency()*1e4)/1e4)
+				}
+			}
+			converted.Levels = append(converted.Levels, level)
+		}
+		sort.Slice(converted.Levels, func(i, j int) bool { return converted.Levels[i].Load < converted.Levels[j].Load })
+		report.Series = append(report.Series, converted)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating file: %v", err)
+	}
+	defer file.Close()
+	if err := htmlTemplate.Execute(file, report); err != nil {
+		return fmt.Errorf("error writing HTML report: %v", err)
+	}
+	return nil
+}
+
+// tailPercentile returns the key of the percentile drawn as the tail: p95 if it
+// was computed, the highest one otherwise, and "" if there are none.
+func tailPercentile(percentiles map[string]float64) string {
+	if _, ok := percentiles["p95"]; ok {
+		return "p95"
+	}
+	tail, highest := "", -1.0
+	for key := range percentiles {
+		p, _ := strconv.ParseFloat(strings.TrimPrefix(key, "p"), 64)
+		if p > highest {
+			tail, highest = key, p
+		}
+	}
+	return tail
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>LLM API Throughput Benchmark</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 2em auto; max-width: 1100px; color: #222; padding: 0 1em; }
+h1 { margin-bottom: 0.2em; }
+.meta { color: #666; margin-bottom: 2em; }
+.grid { display: grid; grid-template-columns: repeat(auto-fit, minmax(480px, 1fr)); gap: 2em; }
+.chart h2 { font-size: 1.05em; margin: 0 0 0.5em; }
+svg { width: 100%; height: auto; overflow: visible; }
+svg text { font-size: 11px; fill: #555; }
+.axis line, .axis path { stroke: #999; }
+.gridline { stroke: #eee; }
+.legend span { cursor: pointer; margin-right: 1.2em; user-select: none; }
+.legend span.off { opacity: 0.35; }
+.legend i { display: inline-block; width: 12px; height: 12px; border-radius: 2px; margin-right: 0.4em; vertical-align: -1px; }
+#tooltip { position: fixed; pointer-events: none; background: #222; color: #fff; padding: 4px 8px; border-radius: 4px; font-size: 12px; display: none; white-space: pre; }
+table { border-collapse: collapse; margin: 1em 0 2em; font-size: 13px; }
+th, td { border: 1px solid #ddd; padding: 4px 10px; text-align: right; }
+th { background: #f5f5f5; }
+td:first-child, th:first-child { text-align: left; }
+select { margin-left: 0.5em; }
+</style>
+</head>
+<body>
+<h1>LLM API Throughput Benchmark</h1>
+<div class="meta">Generated {{.Generated}} by <a href="https://github.com/Yoosu-L/llmapibenchmark">llmapibenchmark</a></div>
+{{range .Series}}
+<h3>{{if .Name}}{{.Name}}{{else}}{{.ModelName}}{{end}}</h3>
+<div class="meta">Model: {{.ModelName}} | Latency: {{printf "%.2f" .Latency}} ms | Input: {{.InputTokens}} tokens | Output: {{.MaxTokens}} tokens</div>
+<table>
+<tr><th>{{if .OpenLoop}}Rate{{else}}Conc{{end}}</th><th>Gen TPS</th><th>Prompt TPS</th><th>Req/s</th><th>TTFT P50(s)</th><th>E2E P50(s)</th><th>User TPS P50</th><th>Success</th></tr>
+{{range .Levels}}<tr><td>{{.Load}}</td><td>{{printf "%.2f" .GenTps}}</td><td>{{printf "%.2f" .PromptTps}}</td><td>{{printf "%.2f" .ReqPerSec}}</td><td>{{printf "%.2f" .TtftP50}}</td><td>{{printf "%.2f" .E2eP50}}</td><td>{{printf "%.2f" .UserTps}}</td><td>{{printf "%.2f" .Success}}</td></tr>
+{{end}}</table>
+{{end}}
+<div class="legend" id="legend"></div>
+<div class="grid">
+<div class="chart"><h2>Generation throughput vs load</h2><div id="throughput"></div></div>
+<div class="chart"><h2>TTFT vs load (solid P50, dashed tail percentile)</h2><div id="ttft"></div></div>
+<div class="chart"><h2>Latency / throughput trade-off (Pareto front highlighted)</h2><div id="pareto"></div></div>
+<div class="chart"><h2>Per-request latency distribution <select id="level"></select></h2><div id="histograms"></div></div>
+</div>
+<div id="tooltip"></div>
+<script>
+const data = {{.Series}};
+const palette = ["#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#17becf"];
+const hidden = new Set();
+const ns = "http://www.w3.org/2000/svg";
+const tooltip = document.getElementById("tooltip");
+
+function el(tag, attrs, parent) {
+  const node = document.createElementNS(ns, tag);
+  for (const key in attrs) node.setAttribute(key, attrs[key]);
+  if (parent) parent.appendChild(node);
+  return node;
+}
+
+function ticks(min, max, count) {
+  if (max <= min) max = min + 1;
+  const step = Math.pow(10, Math.floor(Math.log10((max - min) / count)));
+  const factor = [1, 2, 5, 10].find(f => (max - min) / (step * f) <= count) || 10;
+  const size = step * factor;
+  const result = [];
+  for (let v = Math.ceil(min / size) * size; v <= max + size * 1e-9; v += size) result.push(+v.toPrecision(12));
+  return result;
+}
+
+function showTip(event, text) {
+  tooltip.textContent = text;
+  tooltip.style.display = "block";
+  tooltip.style.left = (event.clientX + 12) + "px";
+  tooltip.style.top = (event.clientY + 12) + "px";
+}
+function hideTip() { tooltip.style.display = "none"; }
+
+// chart draws line or scatter series of {x, y, tip} points with linear axes,
+// or a logarithmic x axis when logX is set.
+function chart(container, opts) {
+  container.innerHTML = "";
+  const width = 520, height = 300, m = {left: 56, right: 16, top: 10, bottom: 40};
+  const svg = el("svg", {viewBox: "0 0 " + width + " " + height}, container);
+  const points = opts.series.flatMap(s => s.points);
+  if (points.length === 0) return;
+  const xs = points.map(p => p.x), ys = points.map(p => p.y);
+  const logX = opts.logX && Math.min(...xs) > 0 && Math.max(...xs) / Math.min(...xs) >= 8;
+  const fx = logX ? Math.log2 : (v => v);
+  const xMin = logX ? fx(Math.min(...xs)) : Math.min(0, ...xs), xMax = fx(Math.max(...xs));
+  const yMax = Math.max(...ys) * 1.08 || 1;
+  const sx = v => m.left + (fx(v) - xMin) / ((xMax - xMin) || 1) * (width - m.left - m.right);
+  const sy = v => height - m.bottom - v / yMax * (height - m.top - m.bottom);
+
+  const xTicks = logX ? xs.filter((v, i) => xs.indexOf(v) === i).sort((a, b) => a - b) : ticks(xMin, xMax, 6);
+  for (const t of ticks(0, yMax, 5)) {
+    el("line", {x1: m.left, x2: width - m.right, y1: sy(t), y2: sy(t), class: "gridline"}, svg);
+    el("text", {x: m.left - 6, y: sy(t) + 4, "text-anchor": "end"}, svg).textContent = t;
+  }
+  for (const t of xTicks) {
+    el("text", {x: sx(t), y: height - m.bottom + 16, "text-anchor": "middle"}, svg).textContent = t;
+  }
+  const axis = el("g", {class: "axis"}, svg);
+  el("line", {x1: m.left, x2: width - m.right, y1: height - m.bottom, y2: height - m.bottom}, axis);
+  el("line", {x1: m.left, x2: m.left, y1: m.top, y2: height - m.bottom}, axis);
+  el("text", {x: (width + m.left) / 2, y: height - 6, "text-anchor": "middle"}, svg).textContent = opts.xLabel;
+  el("text", {x: -(height - m.bottom) / 2, y: 14, transform: "rotate(-90)", "text-anchor": "middle"}, svg).textContent = opts.yLabel;
+
+  for (const s of opts.series) {
+    if (s.points.length === 0) continue;
+    if (s.line) {
+      const d = s.points.map((p, i) => (i ? "L" : "M") + sx(p.x) + "," + sy(p.y)).join(" ");
+      el("path", {d: d, fill: "none", stroke: s.color, "stroke-width": s.width || 2, "stroke-dasharray": s.dashed ? "5,4" : "none"}, svg);
+    }
+    for (const p of s.points) {
+      const dot = el("circle", {cx: sx(p.x), cy: sy(p.y), r: s.radius || 4, fill: s.hollow ? "#fff" : s.color, stroke: s.color, "stroke-width": 2}, svg);
+      dot.addEventListener("mousemove", e => showTip(e, p.tip));
+      dot.addEventListener("mouseleave", hideTip);
+    }
+  }
+}
+
+// histogram draws the distribution of values in bins of equal width.
+function histogram(container, values, label, color) {
+  const wrapper = document.createElement("div");
+  container.appendChild(wrapper);
+  if (values.length === 0) { wrapper.textContent = "No successful requests"; return; }
+  const min = Math.min(...values), max = Math.max(...values);
+  const bins = Math.min(30, Math.max(5, Math.ceil(Math.sqrt(values.length))));
+  const size = (max - min) / bins || 1;
+  const counts = new Array(bins).fill(0);
+  for (const v of values) counts[Math.min(bins - 1, Math.floor((v - min) / size))]++;
+  const width = 520, height = 180, m = {left: 56, right: 16, top: 10, bottom: 40};
+  const svg = el("svg", {viewBox: "0 0 " + width + " " + height}, wrapper);
+  const top = Math.max(...counts) * 1.1;
+  const barWidth = (width - m.left - m.right) / bins;
+  counts.forEach((count, i) => {
+    const h = count / top * (height - m.top - m.bottom);
+    const bar = el("rect", {x: m.left + i * barWidth + 1, y: height - m.bottom - h, width: barWidth - 2, height: h, fill: color}, svg);
+    const from = min + i * size;
+    bar.addEventListener("mousemove", e => showTip(e, from.toFixed(3) + "-" + (from + size).toFixed(3) + " s: " + count + " requests"));
+    bar.addEventListener("mouseleave", hideTip);
+  });
+  for (const t of ticks(min, min + size * bins, 6)) {
+    el("text", {x: m.left + (t - min) / (size * bins) * (width - m.left - m.right), y: height - m.bottom + 16, "text-anchor": "middle"}, svg).textContent = t;
+  }
+  for (const t of ticks(0, top, 4)) {
+    el("text", {x: m.left - 6, y: height - m.bottom - t / top * (height - m.top - m.bottom) + 4, "text-anchor": "end"}, svg).textContent = t;
+  }
+  el("line", {x1: m.left, x2: width - m.right, y1: height - m.bottom, y2: height - m.bottom, stroke: "#999"}, svg);
+  el("text", {x: (width + m.left) / 2, y: height - 6, "text-anchor": "middle"}, svg).textContent = label;
+}
+
+// paretoFront returns the levels no other level beats on both latency and throughput.
+function paretoFront(points) {
+  const sorted = points.slice().sort((a, b) => a.x - b.x || b.y - a.y);
+  const front = [];
+  for (const p of sorted) if (front.length === 0 || p.y > front[front.length - 1].y) front.push(p);
+  return front;
+}
+
+function loadLabel(s, level) {
+  return s.openLoop ? "rate " + level.load + " (peak conc " + level.concurrency + ")" : "conc " + level.load;
+}
+
+function render() {
+  const visible = data.map((s, i) => ({s: s, color: palette[i % palette.length], name: s.name || s.model})).filter(v => !hidden.has(v.name));
+  const openLoop = data.some(s => s.openLoop);
+  const loadName = openLoop ? "Request rate (req/s)" : "Concurrency";
+
+  chart(document.getElementById("throughput"), {xLabel: loadName, yLabel: "Gen TPS (tokens/s)", logX: true, series: visible.map(v => ({
+    color: v.color, line: true,
+    points: v.s.levels.map(l => ({x: l.load, y: l.genTps, tip: v.name + "\n" + loadLabel(v.s, l) + "\nGen TPS " + l.genTps + "\nPrompt TPS " + l.promptTps})),
+  }))});
+
+  chart(document.getElementById("ttft"), {xLabel: loadName, yLabel: "TTFT (s)", logX: true, series: visible.flatMap(v => [
+    {color: v.color, line: true, points: v.s.levels.map(l => ({x: l.load, y: l.ttftP50, tip: v.name + "\n" + loadLabel(v.s, l) + "\nTTFT P50 " + l.ttftP50 + " s"}))},
+    {color: v.color, line: true, dashed: true, hollow: true, points: v.s.levels.filter(l => l.tailKey).map(l => ({x: l.load, y: l.ttftTail, tip: v.name + "\n" + loadLabel(v.s, l) + "\nTTFT " + l.tailKey.toUpperCase() + " " + l.ttftTail + " s"}))},
+  ])});
+
+  chart(document.getElementById("pareto"), {xLabel: "E2E latency P50 (s)", yLabel: "Gen TPS (tokens/s)", series: visible.flatMap(v => {
+    const points = v.s.levels.map(l => ({x: l.e2eP50, y: l.genTps, tip: v.name + "\n" + loadLabel(v.s, l) + "\nE2E P50 " + l.e2eP50 + " s\nGen TPS " + l.genTps + "\nUser TPS P50 " + l.userTps}));
+    return [
+      {color: v.color, hollow: true, radius: 3, points: points},
+      {color: v.color, line: true, width: 2.5, points: paretoFront(points)},
+    ];
+  })});
+
+  const select = document.getElementById("level");
+  const [si, li] = (select.value || "0:0").split(":").map(Number);
+  const histograms = document.getElementById("histograms");
+  histograms.innerHTML = "";
+  const s = data[si], level = s && s.levels[li];
+  if (level) {
+    const color = palette[si % palette.length];
+    histogram(histograms, level.ttfts, "TTFT (s)", color);
+    histogram(histograms, level.latencies, "End-to-end latency (s)", color);
+  }
+}
+
+const legend = document.getElementById("legend");
+data.forEach((s, i) => {
+  const name = s.name || s.model;
+  const item = document.createElement("span");
+  item.innerHTML = "<i></i>";
+  item.firstChild.style.background = palette[i % palette.length];
+  item.appendChild(document.createTextNode(name));
+  item.addEventListener("click", () => {
+    if (hidden.has(name)) hidden.delete(name); else hidden.add(name);
+    item.classList.toggle("off");
+    render();
+  });
+  legend.appendChild(item);
+});
+
+const select = document.getElementById("level");
+data.forEach((s, si) => s.levels.forEach((l, li) => {
+  const option = document.createElement("option");
+  option.value = si + ":" + li;
+  option.textContent = (data.length > 1 ? (s.name || s.model) + ", " : "") + loadLabel(s, l);
+  select.appendChild(option);
+}));
+select.addEventListener("change", render);
+render();
+</script>
+</body>
+</html>
+`))
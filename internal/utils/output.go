@@ -43,8 +43,219 @@ func PrintBenchmarkHeader(modelName string, inputTokens int, maxTokens int, late
 	fmt.Printf("%s%sInput:%s %-25d | %sOutput:%s  %d tokens%s\n\n", green, bold, reset+green, inputTokens, bold, reset+green, maxTokens, reset)
 }
 
-// SaveResultsToMD saves the benchmark results to a Markdown file.
-func SaveResultsToMD(results [][]interface{}, modelName string, inputTokens int, maxTokens int, latency float64) {
+// ResultsTableHeader returns the header and alignment rows of the results table.
+// Open-loop runs are keyed by request rate and report the peak concurrency reached.
+func ResultsTableHeader(openLoop bool) (string, string) {
+	if openLoop {
+		return "|  Rate  | Peak Conc | Req/s | Gen TPS | Prompt TPS | Min TTFT(s) | Max TTFT(s) | Success | Total(s) |",
+			"|:------:|:---------:|:-----:|:-------:|:----------:|:-----------:|:-----------:|:-------:|:--------:|"
+	}
+	return "| Conc | Gen TPS | Prompt TPS | Min TTFT(s) | Max TTFT(s) | Success | Total(s) |",
+		"|:----:|:-------:|:----------:|:-----------:|:-----------:|:-------:|:--------:|"
+}
+
+// ResultsTableRow formats a single result as a row of the results table.
+func ResultsTableRow(result SpeedResult, openLoop bool) string {
+	common := fmt.Sprintf("%7.2f | %10.2f | %11.2f | %11.2f | %6.2f%% | %8.2f |",
+		result.GenerationSpeed,
+		result.PromptThroughput,
+		result.MinTtft,
+		result.MaxTtft,
+		result.SuccessRate*100,
+		result.Duration,
+	)
+	if openLoop {
+		return fmt.Sprintf("| %6.2f | %9d | %5.2f | %s", result.Rate, result.Concurrency, result.RequestThroughput, common)
+	}
+	return fmt.Sprintf("| %4d | %s", result.Concurrency, common)
+}
+
+// LevelTable is a table with one row per level, mostly listing the mean, median
+// and each percentile of per-request distributions.
+type LevelTable struct {
+	openLoop bool
+	columns  []tableColumn
+}
+
+type tableColumn struct {
+	title string
+	value func(SpeedResult) float64
+}
+
+// LatencyTable returns the table of TTFT and end-to-end latency distributions.
+func LatencyTable(percentiles []float64, openLoop bool) LevelTable {
+	table := LevelTable{openLoop: openLoop}
+	table.addDistribution("TTFT", "s", percentiles, func(result SpeedResult) Distribution { return result.Ttft })
+	table.addDistribution("E2E", "s", percentiles, func(result SpeedResult) Distribution { return result.E2eLatency })
+	return table
+}
+
+// DecodeTable returns the table of TPOT and inter-token latency distributions.
+func DecodeTable(percentiles []float64, openLoop bool) LevelTable {
+	table := LevelTable{openLoop: openLoop}
+	table.addDistribution("TPOT", "ms", percentiles, func(result SpeedResult) Distribution { return result.Tpot })
+	table.addDistribution("ITL", "ms", percentiles, func(result SpeedResult) Distribution { return result.Itl })
+	table.columns = append(table.columns, tableColumn{"Max Stall(ms)", func(result SpeedResult) float64 { return result.MaxStall }})
+	return table
+}
+
+// UserSpeedTable returns the table of per-request decode speed distributions.
+func UserSpeedTable(percentiles []float64, openLoop bool) LevelTable {
+	table := LevelTable{openLoop: openLoop}
+	table.addDistribution("User TPS", "tok/s", percentiles, func(result SpeedResult) Distribution { return result.UserSpeed })
+	return table
+}
+
+// GoodputTable returns the table of requests and tokens per second that met every SLO.
+func GoodputTable(openLoop bool) LevelTable {
+	goodput := func(result SpeedResult) Goodput {
+		if result.Goodput == nil {
+			return Goodput{}
+		}
+		return *result.Goodput
+	}
+	return LevelTable{openLoop: openLoop, columns: []tableColumn{
+		{"Goodput(req/s)", func(result SpeedResult) float64 { return goodput(result).RequestRate }},
+		{"Goodput(tok/s)", func(result SpeedResult) float64 { return goodput(result).TokenRate }},
+		{"SLO Met(%)", func(result SpeedResult) float64 { return goodput(result).Attainment * 100 }},
+		{"Success(%)", func(result SpeedResult) float64 { return result.SuccessRate * 100 }},
+	}}
+}
+
+// ServerTable returns the table comparing the prefill and decode speeds the
+// server reported with the ones measured on the client, and the prompt tokens
+// served from its cache if it reports them.
+func ServerTable(openLoop bool, cached bool) LevelTable {
+	server := func(result SpeedResult) ServerSpeed {
+		if result.Server == nil {
+			return ServerSpeed{}
+		}
+		return *result.Server
+	}
+	table := LevelTable{openLoop: openLoop, columns: []tableColumn{
+		{"Server Prefill TPS P50", func(result SpeedResult) float64 { return server(result).PromptSpeed.Median }},
+		{"Server Decode TPS P50", func(result SpeedResult) float64 { return server(result).DecodeSpeed.Median }},
+		{"Client User TPS P50", func(result SpeedResult) float64 { return result.UserSpeed.Median }},
+		{"Client TTFT P50(s)", func(result SpeedResult) float64 { return result.Ttft.Median }},
+		{"TTFT Overhead P50(s)", func(result SpeedResult) float64 { return server(result).TtftOverhead.Median }},
+	}}
+	if cached {
+		table.columns = append(table.columns, tableColumn{"Cached Prompt Mean(tok)", func(result SpeedResult) float64 {
+			if cachedTokens := server(result).CachedTokens; cachedTokens != nil {
+				return cachedTokens.Mean
+			}
+			return 0
+		}})
+	}
+	return table
+}
+
+// LengthTable returns the table of realised prompt and completion length distributions.
+func LengthTable(percentiles []float64, openLoop bool) LevelTable {
+	table := LevelTable{openLoop: openLoop}
+	table.addDistribution("Input", "tok", percentiles, func(result SpeedResult) Distribution { return result.InputLength })
+	table.addDistribution("Output", "tok", percentiles, func(result SpeedResult) Distribution { return result.OutputLength })
+	return table
+}
+
+// TrialsTable returns the table of the spread of the headline metrics over the
+// trials of each level when levels are measured several times.
+func TrialsTable(openLoop bool) LevelTable {
+	table := LevelTable{openLoop: openLoop}
+	table.addEstimate("Gen TPS", "", "generation_speed")
+	table.addEstimate("Prompt TPS", "", "prompt_throughput")
+	table.addEstimate("TTFT P50", "(s)", "ttft.median")
+	table.addEstimate("E2E P50", "(s)", "e2e_latency.median")
+	return table
+}
+
+func (table *LevelTable) addEstimate(metric string, unit string, path string) {
+	estimate := func(result SpeedResult) Estimate {
+		if result.Trials == nil {
+			return Estimate{}
+		}
+		return result.Trials.Fields[path]
+	}
+	table.columns = append(table.columns,
+		tableColumn{fmt.Sprintf("%s SD%s", metric, unit), func(result SpeedResult) float64 { return estimate(result).Stddev }},
+		tableColumn{fmt.Sprintf("%s 95%% CI(+/-)%s", metric, unit), func(result SpeedResult) float64 { return estimate(result).HalfWidth() }},
+	)
+}
+
+// LevelTables returns the tables reported after the results table in every run.
+func LevelTables(percentiles []float64, openLoop bool) []LevelTable {
+	return []LevelTable{
+		LatencyTable(percentiles, openLoop),
+		DecodeTable(percentiles, openLoop),
+		UserSpeedTable(percentiles, openLoop),
+	}
+}
+
+func (table *LevelTable) addDistribution(metric string, unit string, percentiles []float64, get func(SpeedResult) Distribution) {
+	table.columns = append(table.columns,
+		tableColumn{fmt.Sprintf("%s Mean(%s)", metric, unit), func(result SpeedResult) float64 { return get(result).Mean }},
+		tableColumn{fmt.Sprintf("%s P50(%s)", metric, unit), func(result SpeedResult) float64 { return get(result).Median }},
+	)
+	for _, p := range tablePercentiles(percentiles) {
+		key := PercentileKey(p)
+		table.columns = append(table.columns, tableColumn{
+			fmt.Sprintf("%s %s(%s)", metric, strings.ToUpper(key), unit),
+			func(result SpeedResult) float64 { return get(result).Percentiles[key] },
+		})
+	}
+}
+
+func (table LevelTable) levelTitle() string {
+	if table.openLoop {
+		return " Rate "
+	}
+	return "Conc"
+}
+
+// Header returns the header and alignment rows of the table.
+func (table LevelTable) Header() (string, string) {
+	titles := []string{table.levelTitle()}
+	for _, column := range table.columns {
+		titles = append(titles, column.title)
+	}
+
+	cells := make([]string, len(titles))
+	separators := make([]string, len(titles))
+	for i, title := range titles {
+		cells[i] = " " + title + " "
+		separators[i] = ":" + strings.Repeat("-", len(title)) + ":"
+	}
+	return "|" + strings.Join(cells, "|") + "|", "|" + strings.Join(separators, "|") + "|"
+}
+
+// Row formats a single result as a row of the table.
+func (table LevelTable) Row(result SpeedResult) string {
+	level := fmt.Sprintf(" %*d ", len(table.levelTitle()), result.Concurrency)
+	if table.openLoop {
+		level = fmt.Sprintf(" %*.2f ", len(table.levelTitle()), result.Rate)
+	}
+
+	cells := []string{level}
+	for _, column := range table.columns {
+		cells = append(cells, fmt.Sprintf(" %*.2f ", len(column.title), column.value(result)))
+	}
+	return "|" + strings.Join(cells, "|") + "|"
+}
+
+// tablePercentiles drops p50 from percentiles since tables always show the median.
+func tablePercentiles(percentiles []float64) []float64 {
+	var filtered []float64
+	for _, p := range percentiles {
+		if p != 50 {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// SaveResultsToMD saves the benchmark results to a Markdown file. A label, if
+// set, is added to the file name to keep runs of the same model apart.
+func SaveResultsToMD(results []SpeedResult, openLoop bool, tables []LevelTable, modelName string, label string, inputTokens int, maxTokens int, latency float64) {
 	// sanitize modelName to create a safe filename (replace path separators)
 	safeModelName := strings.ReplaceAll(modelName, "/", "_")
 	safeModelName = strings.ReplaceAll(safeModelName, "\\", "_")
@@ -53,6 +264,15 @@ func SaveResultsToMD(results [][]interface{}, modelName string, inputTokens int,
 		safeModelName = "model"
 	}
 	filename := fmt.Sprintf("API_Throughput_%s.md", safeModelName)
+	if label != "" {
+		safeLabel := strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+				return r
+			}
+			return '_'
+		}, label)
+		filename = fmt.Sprintf("API_Throughput_%s_%s.md", safeModelName, safeLabel)
+	}
 	file, err := os.Create(filename)
 	if err != nil {
 		log.Printf("Error creating file: %v", err)
@@ -61,26 +281,21 @@ func SaveResultsToMD(results [][]interface{}, modelName string, inputTokens int,
 	defer file.Close()
 
 	file.WriteString(fmt.Sprintf("```\nModel: %s\nLatency: %.2f ms\nInput: %d tokens / Output: %d tokens\n```\n\n", modelName, latency, inputTokens, maxTokens))
-	file.WriteString("| Conc | Gen TPS | Prompt TPS | Min TTFT(s) | Max TTFT(s) | Success | Total(s) |\n")
-	file.WriteString("|:----:|:-------:|:----------:|:-----------:|:-----------:|:-------:|:--------:|\n")
+	header, separator := ResultsTableHeader(openLoop)
+	file.WriteString(header + "\n")
+	file.WriteString(separator + "\n")
 
 	for _, result := range results {
-		concurrency := result[0].(int)
-		generationSpeed := result[1].(float64)
-		promptThroughput := result[2].(float64)
-		minTTFT := result[3].(float64)
-		maxTTFT := result[4].(float64)
-		successRate := result[5].(float64)
-		duration := result[6].(float64)
-		file.WriteString(fmt.Sprintf("| %4d | %7.2f | %10.2f | %11.2f | %11.2f | %6.2f%% | %8.2f |\n",
-			concurrency,
-			generationSpeed,
-			promptThroughput,
-			minTTFT,
-			maxTTFT,
-			successRate*100,
-			duration,
-		))
+		file.WriteString(ResultsTableRow(result, openLoop) + "\n")
+	}
+
+	for _, table := range tables {
+		header, separator = table.Header()
+		file.WriteString("\n" + header + "\n")
+		file.WriteString(separator + "\n")
+		for _, result := range results {
+			file.WriteString(table.Row(result) + "\n")
+		}
 	}
 
 	fmt.Printf("Results saved to: %s\n\n", filename)
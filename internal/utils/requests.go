@@ -0,0 +1,39 @@
+package utils
+
+import "time"
+
+// RequestRecord describes one request sent while measuring a level, successful
+// or not. Records are kept in memory for exports and left out of the JSON and
+// YAML results.
+type RequestRecord struct {
+	Concurrency      int       // Concurrency level, 0 for open-loop levels
+	Rate             float64   // Request rate of open-loop levels
+	Scheduled        time.Time // When the request was due to be sent, its arrival time on open-loop levels
+	Start            time.Time
+	FirstToken       time.Time // Zero if no token arrived
+	End              time.Time
+	PromptTokens     int
+	CompletionTokens int
+	Error            string // Empty for successful requests
+
+	UsageReported             bool // Whether the server reported the token counts, otherwise they are estimates
+	EstimatedPromptTokens     int
+	EstimatedCompletionTokens int
+	FinishReason              string
+	HTTPStatus                int    // 0 if the server never answered
+	ErrorClass                string // Coarse cause of the error, see api.ClassifyError
+	ChunkCount                int
+}
+
+// Ttft returns the seconds from the start of the request to the first token.
+func (record RequestRecord) Ttft() float64 {
+	if record.FirstToken.IsZero() {
+		return 0
+	}
+	return record.FirstToken.Sub(record.Start).Seconds()
+}
+
+// Latency returns the seconds from the start to the end of the request.
+func (record RequestRecord) Latency() float64 {
+	return record.End.Sub(record.Start).Seconds()
+}
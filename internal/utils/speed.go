@@ -2,20 +2,21 @@ package utils
 
 import (
 	"math"
+	"math/rand"
+	"net/http"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/Yoosu-L/llmapibenchmark/internal/api"
+	"github.com/Yoosu-L/llmapibenchmark/internal/workload"
 
-	"github.com/sashabaranov/go-openai"
 	"github.com/schollz/progressbar/v3"
 )
 
 type SpeedMeasurement struct {
-	BaseUrl        string
-	ApiVersion     string
-	ApiKey         string
+	Client         api.Provider // Sends the requests, shared by all of them
 	ModelName      string
 	Prompt         string
 	UseRandomInput bool
@@ -23,103 +24,338 @@ type SpeedMeasurement struct {
 	MaxTokens      int
 	Latency        float64
 	Concurrency    int
+
+	// Workload overrides Prompt and random input with a sample per request when set
+	Workload workload.Source
+
+	// Open-loop load: when Rate is above 0, requests arrive at Rate requests/sec
+	// following the Arrival process instead of a single burst of Concurrency requests.
+	Rate    float64
+	Arrival string
+	Seed    int64
+
+	// Duration-based levels: when Duration is above 0, load is sustained for
+	// Warmup+Duration and only the last Duration is measured. Closed levels keep
+	// Concurrency requests in flight for the whole time.
+	Duration time.Duration
+	Warmup   time.Duration
+
+	// Percentiles reported for the per-request latency distributions
+	Percentiles []float64
+
+	// Slo enables goodput reporting when any of its objectives is set
+	Slo SLO
+}
+
+// SLO holds per-request service level objectives. Zero objectives are not checked.
+type SLO struct {
+	Ttft time.Duration
+	Tpot time.Duration
+	E2e  time.Duration
+}
+
+// Enabled reports whether any objective is set.
+func (slo SLO) Enabled() bool {
+	return slo.Ttft > 0 || slo.Tpot > 0 || slo.E2e > 0
+}
+
+// met reports whether a successful request met every objective.
+func (slo SLO) met(sample requestSample) bool {
+	if slo.Ttft > 0 && sample.firstToken.Sub(sample.start) > slo.Ttft {
+		return false
+	}
+	if slo.E2e > 0 && sample.end.Sub(sample.start) > slo.E2e {
+		return false
+	}
+	if tpot, ok := sample.tpot(); slo.Tpot > 0 && ok && tpot > float64(slo.Tpot)/float64(time.Millisecond) {
+		return false
+	}
+	return true
+}
+
+// Goodput is the throughput of requests that met every SLO.
+type Goodput struct {
+	RequestRate float64 `json:"request_rate" yaml:"request-rate"` // Requests/s meeting every SLO
+	TokenRate   float64 `json:"token_rate" yaml:"token-rate"`     // Output tokens/s from those requests
+	Attainment  float64 `json:"attainment" yaml:"attainment"`     // Fraction of all requests meeting every SLO
 }
 
 type SpeedResult struct {
-	Concurrency      int     `json:"concurrency" yaml:"concurrency"`
-	GenerationSpeed  float64 `json:"generation_speed" yaml:"generation-speed"`
-	PromptThroughput float64 `json:"prompt_throughput" yaml:"prompt-throughput"`
-	MaxTtft          float64 `json:"max_ttft" yaml:"max-ttft"`
-	MinTtft          float64 `json:"min_ttft" yaml:"min-ttft"`
-	SuccessRate      float64 `json:"success_rate" yaml:"success-rate"`
-	Duration         float64 `json:"duration" yaml:"duration"`
+	Concurrency       int     `json:"concurrency" yaml:"concurrency"`
+	Rate              float64 `json:"rate,omitempty" yaml:"rate,omitempty"`
+	RequestThroughput float64 `json:"request_throughput" yaml:"request-throughput"`
+	GenerationSpeed   float64 `json:"generation_speed" yaml:"generation-speed"`
+	PromptThroughput  float64 `json:"prompt_throughput" yaml:"prompt-throughput"`
+	MaxTtft           float64 `json:"max_ttft" yaml:"max-ttft"`
+	MinTtft           float64 `json:"min_ttft" yaml:"min-ttft"`
+	SuccessRate       float64 `json:"success_rate" yaml:"success-rate"`
+	Duration          float64 `json:"duration" yaml:"duration"`
+
+	// Per-request latency distributions, in seconds
+	Ttft       Distribution `json:"ttft" yaml:"ttft"`
+	E2eLatency Distribution `json:"e2e_latency" yaml:"e2e-latency"`
+
+	// Per-request decode timings, in milliseconds
+	Tpot     Distribution `json:"tpot_ms" yaml:"tpot-ms"`
+	Itl      Distribution `json:"itl_ms" yaml:"itl-ms"`
+	MaxStall float64      `json:"max_stall_ms" yaml:"max-stall-ms"`
+
+	// Per-request decode speed in tokens/s as experienced by each user, unlike the
+	// aggregate GenerationSpeed. Percentiles describe the slow tail.
+	UserSpeed Distribution `json:"user_speed" yaml:"user-speed"`
+
+	// Only set when SLOs are configured
+	Goodput *Goodput `json:"goodput,omitempty" yaml:"goodput,omitempty"`
+
+	// Realised per-request prompt and completion lengths, in tokens
+	InputLength  Distribution `json:"input_length" yaml:"input-length"`
+	OutputLength Distribution `json:"output_length" yaml:"output-length"`
+
+	// Only set when the server reports its own timings
+	Server *ServerSpeed `json:"server,omitempty" yaml:"server,omitempty"`
+
+	// Only set when each level is measured several times, the fields above then
+	// hold the means over all trials
+	Trials *Trials `json:"trials,omitempty" yaml:"trials,omitempty"`
+
+	// Every request sent at this level
+	Requests []RequestRecord `json:"-" yaml:"-"`
+}
+
+// ServerSpeed holds the per-request prefill and decode speeds the server measured
+// itself, next to the client-side numbers which also include network and queueing.
+type ServerSpeed struct {
+	PromptSpeed  Distribution `json:"prompt_speed" yaml:"prompt-speed"`   // Prefill tokens/s
+	DecodeSpeed  Distribution `json:"decode_speed" yaml:"decode-speed"`   // Decode tokens/s
+	TtftOverhead Distribution `json:"ttft_overhead" yaml:"ttft-overhead"` // Seconds of TTFT spent outside the server's prefill
+
+	// Per-request prompt tokens reused from the server's cache, only set if it reports them
+	CachedTokens *Distribution `json:"cached_tokens,omitempty" yaml:"cached-tokens,omitempty"`
 }
 
+const (
+	ArrivalPoisson  = "poisson"
+	ArrivalConstant = "constant"
+)
+
 func roundToTwoDecimals(f float64) float64 {
 	return math.Round(f*100) / 100
 }
 
+// requestSample holds the timings and token counts of one successful request.
+type requestSample struct {
+	start            time.Time
+	firstToken       time.Time
+	end              time.Time
+	chunkTimes       []float64 // Seconds since start for each content chunk
+	promptTokens     int
+	completionTokens int
+	server           *api.ServerTimings // Nil unless the server reported its timings
+}
+
+// decodeTime returns the seconds between the first and the last content chunk.
+func (sample requestSample) decodeTime() float64 {
+	if len(sample.chunkTimes) == 0 {
+		return 0
+	}
+	return sample.chunkTimes[len(sample.chunkTimes)-1] - sample.chunkTimes[0]
+}
+
+// tpot returns the time per output token after the first in milliseconds, and
+// false if the request produced too few tokens for it to be defined.
+func (sample requestSample) tpot() (float64, bool) {
+	if len(sample.chunkTimes) == 0 || sample.completionTokens <= 1 {
+		return 0, false
+	}
+	return sample.decodeTime() * 1000 / float64(sample.completionTokens-1), true
+}
+
 // Run measures API generation throughput and TTFT.
 func (setup *SpeedMeasurement) Run(bar *progressbar.ProgressBar) (SpeedResult, error) {
-	config := openai.DefaultConfig(setup.ApiKey)
-	config.BaseURL = setup.BaseUrl
-	config.APIVersion = setup.ApiVersion
-	client := openai.NewClientWithConfig(config)
+	var progress func(tokens int)
+	if bar != nil {
+		progress = func(tokens int) { bar.Add(tokens) }
+	}
 
 	var wg sync.WaitGroup
-	var responseTokens sync.Map
-	var promptTokens sync.Map
-	var ttfts sync.Map
-	var successfulRequests atomic.Int32
-	var failedRequests atomic.Int32
+	var samplesMu sync.Mutex
+	var samples []requestSample
+	var failures []time.Time // End times of failed requests
+	var records []RequestRecord
+	var inFlight, peakInFlight atomic.Int32
 
-	start := time.Now()
+	send := func(index int, scheduled time.Time) {
+		defer wg.Done()
+		defer inFlight.Add(-1)
+		current := inFlight.Add(1)
+		for peak := peakInFlight.Load(); current > peak; peak = peakInFlight.Load() {
+			if peakInFlight.CompareAndSwap(peak, current) {
+				break
+			}
+		}
 
-	// Send requests concurrently (restored from debugging version)
-	for i := 0; i < setup.Concurrency; i++ {
-		wg.Add(1)
-		go func(index int) {
-			defer wg.Done()
-			var ttft float64
-			var completionTokens, inputTokens int
-			var err error
-			if setup.UseRandomInput {
-				ttft, completionTokens, inputTokens, err = api.AskOpenAiRandomInput(client, setup.ModelName, setup.NumWords, setup.MaxTokens, bar)
-			} else {
-				ttft, completionTokens, inputTokens, err = api.AskOpenAi(client, setup.ModelName, setup.Prompt, setup.MaxTokens, bar)
+		requestStart := time.Now()
+		var stats api.StreamStats
+		var err error
+		if setup.Workload != nil {
+			sample := setup.Workload.Next()
+			maxTokens := setup.MaxTokens
+			if sample.MaxTokens > 0 {
+				maxTokens = sample.MaxTokens
 			}
-			if err != nil {
-				failedRequests.Add(1)
-				return
+			stats, err = setup.Client.Stream(setup.ModelName, sample.Prompt, maxTokens, progress)
+		} else if setup.UseRandomInput {
+			stats, err = setup.Client.Stream(setup.ModelName, api.GenerateRandomPhrase(setup.NumWords), setup.MaxTokens, progress)
+		} else {
+			stats, err = setup.Client.Stream(setup.ModelName, setup.Prompt, setup.MaxTokens, progress)
+		}
+		end := time.Now()
+		record := RequestRecord{
+			Scheduled:                 scheduled,
+			Start:                     requestStart,
+			End:                       end,
+			UsageReported:             stats.UsageReported,
+			EstimatedPromptTokens:     stats.EstimatedPromptTokens,
+			EstimatedCompletionTokens: stats.EstimatedCompletionTokens,
+			FinishReason:              stats.FinishReason,
+			ChunkCount:                stats.ChunkCount,
+		}
+		if setup.Rate > 0 {
+			record.Rate = setup.Rate
+		} else {
+			record.Concurrency = setup.Concurrency
+		}
+		if err != nil {
+			record.Error = err.Error()
+			record.HTTPStatus, record.ErrorClass = api.ClassifyError(err)
+			if stats.TimeToFirstToken > 0 {
+				record.FirstToken = requestStart.Add(time.Duration(stats.TimeToFirstToken * float64(time.Second)))
 			}
-			successfulRequests.Add(1)
-			ttfts.Store(index, ttft)
-			responseTokens.Store(index, completionTokens)
-			promptTokens.Store(index, inputTokens)
-		}(i)
+			samplesMu.Lock()
+			failures = append(failures, end)
+			records = append(records, record)
+			samplesMu.Unlock()
+			return
+		}
+
+		sample := requestSample{
+			start:            requestStart,
+			firstToken:       requestStart.Add(time.Duration(stats.TimeToFirstToken * float64(time.Second))),
+			end:              end,
+			chunkTimes:       stats.ChunkTimes,
+			promptTokens:     stats.PromptTokens,
+			completionTokens: stats.CompletionTokens,
+			server:           stats.Server,
+		}
+		record.FirstToken = sample.firstToken
+		record.HTTPStatus = http.StatusOK
+		record.PromptTokens = sample.promptTokens
+		record.CompletionTokens = sample.completionTokens
+		samplesMu.Lock()
+		samples = append(samples, sample)
+		records = append(records, record)
+		samplesMu.Unlock()
 	}
 
-	wg.Wait()
-	duration := time.Since(start)
+	start := time.Now()
 
-	// Calculate total tokens
-	totalResponseTokens := 0
-	responseTokens.Range(func(_, value interface{}) bool {
-		totalResponseTokens += value.(int)
-		return true
-	})
+	totalRequests := setup.Concurrency
+	if setup.Rate > 0 {
+		totalRequests = setup.dispatchOpenLoop(&wg, send)
+	} else if setup.Duration > 0 {
+		totalRequests = setup.dispatchSteadyState(&wg, send)
+	} else {
+		// Send requests concurrently (restored from debugging version)
+		for i := 0; i < setup.Concurrency; i++ {
+			wg.Add(1)
+			go send(i, start)
+		}
+	}
 
-	totalPromptTokens := 0
-	promptTokens.Range(func(_, value interface{}) bool {
-		totalPromptTokens += value.(int)
-		return true
-	})
+	wg.Wait()
+	duration := time.Since(start)
 
 	measurement := SpeedResult{}
 	measurement.Concurrency = setup.Concurrency
+	sort.Slice(records, func(i, j int) bool { return records[i].Start.Before(records[j].Start) })
+	measurement.Requests = records
+	if setup.Rate > 0 {
+		// Open-loop levels have no fixed concurrency, report the peak reached instead
+		measurement.Rate = setup.Rate
+		measurement.Concurrency = int(peakInFlight.Load())
+	}
 
 	// Calculate success rate
-	totalRequests := setup.Concurrency
 	if totalRequests > 0 {
-		measurement.SuccessRate = float64(successfulRequests.Load()) / float64(totalRequests)
+		measurement.SuccessRate = float64(len(samples)) / float64(totalRequests)
+	}
+
+	// Duration-based levels only count what happened inside the steady-state window
+	windowStart := start.Add(setup.Warmup)
+	windowEnd := windowStart.Add(setup.Duration)
+	measured := samples
+	if setup.Duration > 0 {
+		// Requests ending during the warm-up or after the window stay out of the latency distributions
+		measured = make([]requestSample, 0, len(samples))
+		for _, sample := range samples {
+			if inWindow(sample.end, windowStart, windowEnd) {
+				measured = append(measured, sample)
+			}
+		}
 	}
 
-	// Calculate max and min TTFT
+	// Calculate max and min TTFT along with the latency distributions
 	measurement.MaxTtft = 0.0
 	measurement.MinTtft = math.Inf(1)
-	ttfts.Range(func(_, value interface{}) bool {
-		ttft := value.(float64)
+	ttfts := make([]float64, 0, len(measured))
+	e2eLatencies := make([]float64, 0, len(measured))
+	inputLengths := make([]float64, 0, len(measured))
+	outputLengths := make([]float64, 0, len(measured))
+	for _, sample := range measured {
+		ttft := sample.firstToken.Sub(sample.start).Seconds()
+		ttfts = append(ttfts, ttft)
+		e2eLatencies = append(e2eLatencies, sample.end.Sub(sample.start).Seconds())
+		inputLengths = append(inputLengths, float64(sample.promptTokens))
+		outputLengths = append(outputLengths, float64(sample.completionTokens))
 		if ttft > measurement.MaxTtft {
 			measurement.MaxTtft = ttft
 		}
 		if ttft < measurement.MinTtft {
 			measurement.MinTtft = ttft
 		}
-		return true
-	})
+	}
+	if len(measured) == 0 {
+		measurement.MinTtft = 0
+	}
 	measurement.MaxTtft = roundToTwoDecimals(measurement.MaxTtft)
 	measurement.MinTtft = roundToTwoDecimals(measurement.MinTtft)
+	measurement.Ttft = NewDistribution(ttfts, setup.Percentiles)
+	measurement.E2eLatency = NewDistribution(e2eLatencies, setup.Percentiles)
+	measurement.InputLength = NewDistribution(inputLengths, setup.Percentiles)
+	measurement.OutputLength = NewDistribution(outputLengths, setup.Percentiles)
+	setup.measureDecode(&measurement, measured)
+	setup.measureServer(&measurement, measured)
+
+	if setup.Duration > 0 {
+		setup.measureWindow(&measurement, samples, windowStart, windowEnd)
+		if setup.Slo.Enabled() {
+			measurement.Goodput = setup.measureGoodput(samples, failures, setup.Duration.Seconds(), func(end time.Time) bool {
+				return inWindow(end, windowStart, windowEnd)
+			})
+		}
+		return measurement, nil
+	}
+
+	// Calculate total tokens
+	totalResponseTokens := 0
+	totalPromptTokens := 0
+	for _, sample := range samples {
+		totalResponseTokens += sample.completionTokens
+		totalPromptTokens += sample.promptTokens
+	}
+
 	measurement.Duration = roundToTwoDecimals(float64(duration.Seconds()))
+	measurement.RequestThroughput = roundToTwoDecimals(float64(len(samples)) / duration.Seconds())
 
 	// Calculate speed (tokens/second)
 	// Ensure we don't divide by zero or negative values
@@ -143,5 +379,201 @@ func (setup *SpeedMeasurement) Run(bar *progressbar.ProgressBar) (SpeedResult, e
 	}
 	measurement.PromptThroughput = roundToTwoDecimals(float64(totalPromptTokens) / promptDuration)
 
+	if setup.Slo.Enabled() {
+		measurement.Goodput = setup.measureGoodput(samples, failures, duration.Seconds(), func(time.Time) bool { return true })
+	}
+
 	return measurement, nil
 }
+
+// measureGoodput computes the goodput over elapsed seconds from the requests
+// whose end time is counted.
+func (setup *SpeedMeasurement) measureGoodput(samples []requestSample, failures []time.Time, elapsed float64, counted func(end time.Time) bool) *Goodput {
+	var total, good, goodTokens int
+	for _, sample := range samples {
+		if !counted(sample.end) {
+			continue
+		}
+		total++
+		if setup.Slo.met(sample) {
+			good++
+			goodTokens += sample.completionTokens
+		}
+	}
+	for _, end := range failures {
+		if counted(end) {
+			total++
+		}
+	}
+
+	goodput := &Goodput{}
+	if elapsed > 0 {
+		goodput.RequestRate = roundToTwoDecimals(float64(good) / elapsed)
+		goodput.TokenRate = roundToTwoDecimals(float64(goodTokens) / elapsed)
+	}
+	if total > 0 {
+		goodput.Attainment = float64(good) / float64(total)
+	}
+	return goodput
+}
+
+// measureWindow fills the throughput fields of measurement from the part of each
+// request that overlaps [windowStart, windowEnd). Completion tokens are assumed to
+// stream evenly between the first token and the end of the request, so requests
+// straddling the window edges are prorated. Prompt tokens and completed requests
+// count towards the window their first token and end fall into respectively.
+func (setup *SpeedMeasurement) measureWindow(measurement *SpeedResult, samples []requestSample, windowStart, windowEnd time.Time) {
+	var responseTokens float64
+	var promptTokens, completedRequests int
+	for _, sample := range samples {
+		decodeStart := sample.firstToken
+		if decodeStart.Before(windowStart) {
+			decodeStart = windowStart
+		}
+		decodeEnd := sample.end
+		if decodeEnd.After(windowEnd) {
+			decodeEnd = windowEnd
+		}
+		if decode := sample.end.Sub(sample.firstToken); decode > 0 && decodeEnd.After(decodeStart) {
+			responseTokens += float64(sample.completionTokens) * float64(decodeEnd.Sub(decodeStart)) / float64(decode)
+		} else if decode <= 0 && inWindow(sample.end, windowStart, windowEnd) {
+			responseTokens += float64(sample.completionTokens)
+		}
+
+		if inWindow(sample.firstToken, windowStart, windowEnd) {
+			promptTokens += sample.promptTokens
+		}
+		if inWindow(sample.end, windowStart, windowEnd) {
+			completedRequests++
+		}
+	}
+
+	window := windowEnd.Sub(windowStart).Seconds()
+	measurement.Duration = roundToTwoDecimals(window)
+	measurement.RequestThroughput = roundToTwoDecimals(float64(completedRequests) / window)
+	measurement.GenerationSpeed = roundToTwoDecimals(responseTokens / window)
+	measurement.PromptThroughput = roundToTwoDecimals(float64(promptTokens) / window)
+}
+
+// measureDecode fills the decode timing fields of measurement. TPOT is the time
+// from the first to the last content chunk divided by the remaining output tokens,
+// ITL the gap between consecutive content chunks, both in milliseconds. The
+// per-user speed is its inverse: the output tokens after the first over that
+// same decode time.
+func (setup *SpeedMeasurement) measureDecode(measurement *SpeedResult, samples []requestSample) {
+	var tpots, itls, userSpeeds []float64
+	maxStall := 0.0
+	for _, sample := range samples {
+		if tpot, ok := sample.tpot(); ok {
+			tpots = append(tpots, tpot)
+		}
+		if decode := sample.decodeTime(); decode > 0 && sample.completionTokens > 1 {
+			userSpeeds = append(userSpeeds, float64(sample.completionTokens-1)/decode)
+		}
+		for i := 1; i < len(sample.chunkTimes); i++ {
+			itl := (sample.chunkTimes[i] - sample.chunkTimes[i-1]) * 1000
+			itls = append(itls, itl)
+			maxStall = math.Max(maxStall, itl)
+		}
+	}
+
+	measurement.Tpot = NewDistribution(tpots, setup.Percentiles)
+	measurement.Itl = NewDistribution(itls, setup.Percentiles)
+	measurement.MaxStall = roundToTwoDecimals(maxStall)
+	measurement.UserSpeed = NewSpeedDistribution(userSpeeds, setup.Percentiles)
+}
+
+// measureServer fills measurement.Server from the timings the server reported,
+// leaving it nil if it reported none.
+func (setup *SpeedMeasurement) measureServer(measurement *SpeedResult, samples []requestSample) {
+	var promptSpeeds, decodeSpeeds, overheads, cachedTokens []float64
+	for _, sample := range samples {
+		server := sample.server
+		if server == nil {
+			continue
+		}
+		if server.CacheReported {
+			cachedTokens = append(cachedTokens, float64(server.CachedTokens))
+		}
+		if server.PromptDuration > 0 {
+			promptSpeeds = append(promptSpeeds, float64(server.PromptTokens)/server.PromptDuration.Seconds())
+		}
+		if server.DecodeDuration > 0 {
+			decodeSpeeds = append(decodeSpeeds, float64(server.DecodeTokens)/server.DecodeDuration.Seconds())
+		}
+		overheads = append(overheads, (sample.firstToken.Sub(sample.start) - server.PromptDuration).Seconds())
+	}
+	if len(overheads) == 0 {
+		return
+	}
+
+	measurement.Server = &ServerSpeed{
+		PromptSpeed:  NewSpeedDistribution(promptSpeeds, setup.Percentiles),
+		DecodeSpeed:  NewSpeedDistribution(decodeSpeeds, setup.Percentiles),
+		TtftOverhead: NewDistribution(overheads, setup.Percentiles),
+	}
+	if len(cachedTokens) > 0 {
+		cached := NewDistribution(cachedTokens, setup.Percentiles)
+		measurement.Server.CachedTokens = &cached
+	}
+}
+
+func inWindow(t, windowStart, windowEnd time.Time) bool {
+	return !t.Before(windowStart) && t.Before(windowEnd)
+}
+
+// dispatchSteadyState keeps setup.Concurrency requests in flight, starting a new
+// request as soon as one finishes, until the warm-up and measurement window have
+// elapsed. It returns the number of requests started.
+func (setup *SpeedMeasurement) dispatchSteadyState(wg *sync.WaitGroup, send func(int, time.Time)) int {
+	deadline := time.Now().Add(setup.Warmup + setup.Duration)
+
+	var workers sync.WaitGroup
+	var dispatched atomic.Int32
+	for i := 0; i < setup.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for time.Now().Before(deadline) {
+				wg.Add(1)
+				send(int(dispatched.Add(1))-1, time.Now())
+			}
+		}()
+	}
+
+	workers.Wait()
+	return int(dispatched.Load())
+}
+
+// dispatchOpenLoop starts a request for every arrival of the configured process
+// until the warm-up and measurement window have elapsed and returns the number
+// of requests started.
+// Arrivals are scheduled against absolute times so slow dispatches don't skew the rate.
+func (setup *SpeedMeasurement) dispatchOpenLoop(wg *sync.WaitGroup, send func(int, time.Time)) int {
+	rng := rand.New(rand.NewSource(setup.Seed))
+	deadline := time.Now().Add(setup.Warmup + setup.Duration)
+	next := time.Now()
+
+	dispatched := 0
+	for {
+		next = next.Add(setup.interArrival(rng))
+		if next.After(deadline) {
+			break
+		}
+		time.Sleep(time.Until(next))
+		wg.Add(1)
+		go send(dispatched, next)
+		dispatched++
+	}
+	return dispatched
+}
+
+// interArrival returns the time until the next open-loop arrival.
+func (setup *SpeedMeasurement) interArrival(rng *rand.Rand) time.Duration {
+	mean := float64(time.Second) / setup.Rate
+	if setup.Arrival == ArrivalConstant {
+		return time.Duration(mean)
+	}
+	// Poisson process: exponentially distributed gaps
+	return time.Duration(rng.ExpFloat64() * mean)
+}
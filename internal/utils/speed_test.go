@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/api"
+)
+
+// slowProvider answers every request with tokens spread evenly over latency.
+type slowProvider struct {
+	latency time.Duration
+	tokens  int
+}
+
+func (provider slowProvider) Stream(model string, prompt string, maxTokens int, progress func(tokens int)) (api.StreamStats, error) {
+	time.Sleep(provider.latency)
+	stats := api.StreamStats{UsageReported: true, PromptTokens: 10, CompletionTokens: provider.tokens, FinishReason: "length"}
+	step := provider.latency.Seconds() / float64(provider.tokens)
+	for i := 0; i < provider.tokens; i++ {
+		stats.ChunkTimes = append(stats.ChunkTimes, step*float64(i+1))
+	}
+	stats.TimeToFirstToken = stats.ChunkTimes[0]
+	return stats, nil
+}
+
+func (provider slowProvider) Models() ([]string, error) {
+	return []string{"slow"}, nil
+}
+
+func TestRunWindowWithoutCompletedRequests(t *testing.T) {
+	setup := SpeedMeasurement{
+		Client:      slowProvider{latency: 300 * time.Millisecond, tokens: 10},
+		MaxTokens:   10,
+		Concurrency: 2,
+		Duration:    50 * time.Millisecond,
+		Percentiles: []float64{95},
+	}
+	result, err := setup.Run(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MinTtft != 0 || result.MaxTtft != 0 {
+		t.Errorf("min, max TTFT = %v, %v, want 0, 0", result.MinTtft, result.MaxTtft)
+	}
+	if result.Ttft.Percentiles != nil || result.RequestThroughput != 0 {
+		t.Errorf("TTFT %+v and %v req/s measured without completed requests", result.Ttft, result.RequestThroughput)
+	}
+	if _, err := json.Marshal(result); err != nil {
+		t.Errorf("result can't be encoded: %v", err)
+	}
+}
+
+func TestRunUserSpeedMatchesTpot(t *testing.T) {
+	// 11 tokens over 220ms: the 10 tokens after the first take 200ms
+	setup := SpeedMeasurement{
+		Client:      slowProvider{latency: 220 * time.Millisecond, tokens: 11},
+		MaxTokens:   11,
+		Concurrency: 1,
+		Percentiles: []float64{95},
+	}
+	result, err := setup.Run(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Tpot.Median != 20 {
+		t.Errorf("median TPOT = %v ms, want 20", result.Tpot.Median)
+	}
+	if result.UserSpeed.Median != 50 {
+		t.Errorf("median user speed = %v, want 50", result.UserSpeed.Median)
+	}
+}
@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Distribution summarises a set of per-request values.
+type Distribution struct {
+	Mean        float64            `json:"mean" yaml:"mean"`
+	Median      float64            `json:"median" yaml:"median"`
+	Percentiles map[string]float64 `json:"percentiles,omitempty" yaml:"percentiles,omitempty"`
+}
+
+// NewDistribution computes the mean, median and requested percentiles of values.
+func NewDistribution(values []float64, percentiles []float64) Distribution {
+	distribution := Distribution{}
+	if len(values) == 0 {
+		return distribution
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, value := range sorted {
+		sum += value
+	}
+	distribution.Mean = roundToTwoDecimals(sum / float64(len(sorted)))
+	distribution.Median = roundToTwoDecimals(Percentile(sorted, 50))
+
+	if len(percentiles) > 0 {
+		distribution.Percentiles = make(map[string]float64, len(percentiles))
+		for _, p := range percentiles {
+			distribution.Percentiles[PercentileKey(p)] = roundToTwoDecimals(Percentile(sorted, p))
+		}
+	}
+	return distribution
+}
+
+// Empty reports whether the distribution was computed from no values. Without
+// any percentiles requested, a distribution of zeros looks empty as well.
+func (distribution Distribution) Empty() bool {
+	return distribution.Mean == 0 && distribution.Median == 0 && len(distribution.Percentiles) == 0
+}
+
+// NewSpeedDistribution is like NewDistribution for rates where lower values are
+// worse: the pX percentile is the value that X% of requests met or exceeded, so
+// high percentiles describe the slow tail just as they do for latencies.
+func NewSpeedDistribution(values []float64, percentiles []float64) Distribution {
+	distribution := NewDistribution(values, nil)
+	if len(values) == 0 || len(percentiles) == 0 {
+		return distribution
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	distribution.Percentiles = make(map[string]float64, len(percentiles))
+	for _, p := range percentiles {
+		distribution.Percentiles[PercentileKey(p)] = roundToTwoDecimals(Percentile(sorted, 100-p))
+	}
+	return distribution
+}
+
+// Percentile returns the p-th percentile (0-100) of sorted values using linear
+// interpolation between the closest ranks.
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// PercentileKey returns the name a percentile is reported under, e.g. "p99.9".
+func PercentileKey(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// ParsePercentiles parses a comma-separated string of percentiles.
+func ParsePercentiles(percentilesStr string) ([]float64, error) {
+	if strings.TrimSpace(percentilesStr) == "" {
+		return nil, nil
+	}
+
+	var percentiles []float64
+	for _, pStr := range strings.Split(percentilesStr, ",") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(pStr), 64)
+		if err != nil {
+			return nil, errors.New("invalid percentile: " + pStr)
+		}
+		if p <= 0 || p > 100 {
+			return nil, errors.New("percentile must be in (0, 100]: " + pStr)
+		}
+		percentiles = append(percentiles, p)
+	}
+
+	sort.Float64s(percentiles)
+	return percentiles, nil
+}
+
+// Comparison describes how a metric changed from sample A to sample B.
+type Comparison struct {
+	A            float64 `json:"a" yaml:"a"` // Mean of A
+	B            float64 `json:"b" yaml:"b"` // Mean of B
+	Delta        float64 `json:"delta" yaml:"delta"`
+	DeltaPercent float64 `json:"delta_percent" yaml:"delta-percent"`
+	CiLow        float64 `json:"ci_low" yaml:"ci-low"`
+	CiHigh       float64 `json:"ci_high" yaml:"ci-high"`
+}
+
+// Significant reports whether the confidence interval of the delta excludes zero.
+func (comparison Comparison) Significant() bool {
+	return comparison.CiLow > 0 || comparison.CiHigh < 0
+}
+
+// Compare returns mean(b) - mean(a) with its confidence interval (e.g. 0.95)
+// from Welch's t-test, which doesn't assume the two samples share a variance.
+func Compare(a, b []float64, confidence float64) Comparison {
+	meanA, varA := meanVariance(a)
+	meanB, varB := meanVariance(b)
+	delta := meanB - meanA
+
+	halfWidth := 0.0
+	seA, seB := varA/float64(len(a)), varB/float64(len(b))
+	if se := seA + seB; se > 0 && len(a) > 1 && len(b) > 1 {
+		// Welch-Satterthwaite degrees of freedom
+		df := se * se / (seA*seA/float64(len(a)-1) + seB*seB/float64(len(b)-1))
+		halfWidth = studentQuantile((1+confidence)/2, df) * math.Sqrt(se)
+	}
+
+	comparison := Comparison{
+		A:      roundToTwoDecimals(meanA),
+		B:      roundToTwoDecimals(meanB),
+		Delta:  roundToTwoDecimals(delta),
+		CiLow:  roundToTwoDecimals(delta - halfWidth),
+		CiHigh: roundToTwoDecimals(delta + halfWidth),
+	}
+	if meanA != 0 {
+		comparison.DeltaPercent = roundToTwoDecimals(delta / meanA * 100)
+	}
+	return comparison
+}
+
+// MeanInterval returns the mean of values and the half-width of its confidence
+// interval (e.g. 0.95) from Student's t distribution. The half-width is 0 with
+// fewer than two values.
+func MeanInterval(values []float64, confidence float64) (float64, float64) {
+	mean, variance := meanVariance(values)
+	if len(values) < 2 {
+		return mean, 0
+	}
+	df := float64(len(values) - 1)
+	return mean, studentQuantile((1+confidence)/2, df) * math.Sqrt(variance/float64(len(values)))
+}
+
+// meanVariance returns the mean and unbiased sample variance of values.
+func meanVariance(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	mean := 0.0
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	variance := 0.0
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	return mean, variance / float64(len(values)-1)
+}
+
+// studentQuantile returns the p-quantile (p >= 0.5) of Student's t distribution
+// with df degrees of freedom, found by bisection on its CDF.
+func studentQuantile(p, df float64) float64 {
+	low, high := 0.0, 1e4
+	for i := 0; i < 100; i++ {
+		mid := (low + high) / 2
+		if studentCDF(mid, df) < p {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// studentCDF returns the CDF of Student's t distribution at t >= 0.
+func studentCDF(t, df float64) float64 {
+	return 1 - 0.5*incompleteBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// incompleteBeta returns the regularized incomplete beta function I_x(a, b),
+// evaluated with the continued fraction from Numerical Recipes.
+func incompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lgammaA, _ := math.Lgamma(a)
+	lgammaB, _ := math.Lgamma(b)
+	lgammaAB, _ := math.Lgamma(a + b)
+	front := math.Exp(lgammaAB - lgammaA - lgammaB + a*math.Log(x) + b*math.Log(1-x))
+	// The continued fraction converges quickly only below this point, use symmetry above it
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaFraction(b, a, 1-x)/b
+	}
+	return front * betaFraction(a, b, x) / a
+}
+
+func betaFraction(a, b, x float64) float64 {
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	result := d
+	for m := 1.0; m <= 200; m++ {
+		for _, numerator := range []float64{
+			m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m)),
+			-(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1)),
+		} {
+			d = 1 + numerator*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + numerator/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			result *= d * c
+		}
+		if math.Abs(d*c-1) < 1e-12 {
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5}
+	for _, test := range []struct {
+		p, want float64
+	}{
+		{0, 1},
+		{50, 3},
+		{90, 4.6},
+		{100, 5},
+	} {
+		if got := Percentile(sorted, test.p); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("Percentile(%v) = %v, want %v", test.p, got, test.want)
+		}
+	}
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("Percentile of no values = %v, want 0", got)
+	}
+}
+
+func TestNewDistribution(t *testing.T) {
+	distribution := NewDistribution([]float64{5, 1, 4, 2, 3}, []float64{90, 99.9})
+	want := Distribution{Mean: 3, Median: 3, Percentiles: map[string]float64{"p90": 4.6, "p99.9": 5}}
+	if !reflect.DeepEqual(distribution, want) {
+		t.Errorf("NewDistribution = %+v, want %+v", distribution, want)
+	}
+	if empty := NewDistribution(nil, []float64{90}); !reflect.DeepEqual(empty, Distribution{}) {
+		t.Errorf("NewDistribution of no values = %+v, want zero", empty)
+	}
+}
+
+func TestParsePercentiles(t *testing.T) {
+	for _, test := range []struct {
+		text    string
+		want    []float64
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"99, 50,90", []float64{50, 90, 99}, false},
+		{"99.9", []float64{99.9}, false},
+		{"0", nil, true},
+		{"101", nil, true},
+		{"p99", nil, true},
+	} {
+		got, err := ParsePercentiles(test.text)
+		if (err != nil) != test.wantErr || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParsePercentiles(%q) = %v, %v, want %v, error %v", test.text, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestNewSpeedDistribution(t *testing.T) {
+	distribution := NewSpeedDistribution([]float64{10, 20, 30, 40, 50}, []float64{90})
+	// p90 of a speed is the value 90% of requests met or exceeded
+	if got := distribution.Percentiles["p90"]; got != 14 {
+		t.Errorf("p90 = %v, want 14", got)
+	}
+	if distribution.Median != 30 || distribution.Mean != 30 {
+		t.Errorf("median, mean = %v, %v, want 30, 30", distribution.Median, distribution.Mean)
+	}
+}
+
+func TestStudentQuantile(t *testing.T) {
+	for _, test := range []struct {
+		p, df, want float64
+	}{
+		{0.975, 1, 12.706},
+		{0.975, 5, 2.571},
+		{0.975, 30, 2.042},
+		{0.95, 10, 1.812},
+		{0.5, 7, 0},
+	} {
+		if got := studentQuantile(test.p, test.df); math.Abs(got-test.want) > 0.001 {
+			t.Errorf("studentQuantile(%v, %v) = %.4f, want %.3f", test.p, test.df, got, test.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a := []float64{10, 12, 11, 13, 9}
+	b := []float64{20, 22, 21, 23, 19}
+	comparison := Compare(a, b, 0.95)
+	if comparison.Delta != 10 || comparison.DeltaPercent != 90.91 {
+		t.Errorf("delta = %v (%v%%), want 10 (90.91%%)", comparison.Delta, comparison.DeltaPercent)
+	}
+	// Equal variances of 2.5 and n=5 give se = 1 and df = 8, t(0.975, 8) = 2.306
+	if math.Abs(comparison.CiLow-7.69) > 0.01 || math.Abs(comparison.CiHigh-12.31) > 0.01 {
+		t.Errorf("interval = [%v, %v], want [7.69, 12.31]", comparison.CiLow, comparison.CiHigh)
+	}
+	if !comparison.Significant() {
+		t.Error("a clear difference is not significant")
+	}
+
+	if Compare(a, a, 0.95).Significant() {
+		t.Error("identical samples differ significantly")
+	}
+	if single := Compare([]float64{1}, []float64{2}, 0.95); single.CiLow != 1 || single.CiHigh != 1 {
+		t.Errorf("interval of single values = [%v, %v], want [1, 1]", single.CiLow, single.CiHigh)
+	}
+}
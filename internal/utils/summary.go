@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SummaryTable compares several benchmarks side by side, with one row per
+// benchmark and level. Rows are labelled by benchmark name and model.
+type SummaryTable struct {
+	nameWidth  int
+	modelWidth int
+}
+
+// NewSummaryTable returns a summary table wide enough for the given names and models.
+func NewSummaryTable(names []string, models []string) SummaryTable {
+	table := SummaryTable{nameWidth: len("Benchmark"), modelWidth: len("Model")}
+	for _, name := range names {
+		table.nameWidth = max(table.nameWidth, len(name))
+	}
+	for _, model := range models {
+		table.modelWidth = max(table.modelWidth, len(model))
+	}
+	return table
+}
+
+// Header returns the header and alignment rows of the table.
+func (table SummaryTable) Header() (string, string) {
+	header := fmt.Sprintf("| %-*s | %-*s |    Load    | Gen TPS | Prompt TPS | TTFT P50(s) | E2E P50(s) | Success |",
+		table.nameWidth, "Benchmark", table.modelWidth, "Model")
+	separator := fmt.Sprintf("|:%s-|:%s-|:----------:|:-------:|:----------:|:-----------:|:----------:|:-------:|",
+		strings.Repeat("-", table.nameWidth), strings.Repeat("-", table.modelWidth))
+	return header, separator
+}
+
+// Row formats a single level of a benchmark as a row of the table.
+func (table SummaryTable) Row(name string, model string, result SpeedResult) string {
+	load := fmt.Sprintf("conc %d", result.Concurrency)
+	if result.Rate > 0 {
+		load = fmt.Sprintf("rate %g", result.Rate)
+	}
+	return fmt.Sprintf("| %-*s | %-*s | %10s | %7.2f | %10.2f | %11.2f | %10.2f | %6.2f%% |",
+		table.nameWidth, name,
+		table.modelWidth, model,
+		load,
+		result.GenerationSpeed,
+		result.PromptThroughput,
+		result.Ttft.Median,
+		result.E2eLatency.Median,
+		result.SuccessRate*100,
+	)
+}
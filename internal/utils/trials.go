@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"math"
+	"reflect"
+	"strings"
+)
+
+// Trials summarises a level measured several times. Fields holds an estimate for
+// every numeric SpeedResult field, keyed by its JSON path, e.g. "ttft.median".
+type Trials struct {
+	Count  int                 `json:"count" yaml:"count"`
+	Fields map[string]Estimate `json:"fields" yaml:"fields"`
+}
+
+// Estimate is the mean of a value over several trials with its spread.
+type Estimate struct {
+	Mean   float64 `json:"mean" yaml:"mean"`
+	Stddev float64 `json:"stddev" yaml:"stddev"`
+	CiLow  float64 `json:"ci_low" yaml:"ci-low"`
+	CiHigh float64 `json:"ci_high" yaml:"ci-high"`
+}
+
+// HalfWidth returns half the width of the confidence interval.
+func (estimate Estimate) HalfWidth() float64 {
+	return (estimate.CiHigh - estimate.CiLow) / 2
+}
+
+// AggregateTrials averages every numeric field of trials, including the
+// distributions, and attaches the per-field estimates with confidence intervals
+// at the given level (e.g. 0.95).
+func AggregateTrials(trials []SpeedResult, confidence float64) SpeedResult {
+	if len(trials) == 0 {
+		return SpeedResult{}
+	}
+
+	samples := map[string][]float64{}
+	for _, trial := range trials {
+		values := map[string]float64{}
+		flattenFields("", reflect.ValueOf(trial), values)
+		for path, value := range values {
+			samples[path] = append(samples[path], value)
+		}
+	}
+
+	estimates := make(map[string]Estimate, len(samples))
+	means := make(map[string]float64, len(samples))
+	for path, values := range samples {
+		mean, halfWidth := MeanInterval(values, confidence)
+		_, variance := meanVariance(values)
+		estimates[path] = Estimate{
+			Mean:   roundToTwoDecimals(mean),
+			Stddev: roundToTwoDecimals(math.Sqrt(variance)),
+			CiLow:  roundToTwoDecimals(mean - halfWidth),
+			CiHigh: roundToTwoDecimals(mean + halfWidth),
+		}
+		means[path] = mean
+	}
+
+	result := trials[0]
+	setFields("", reflect.ValueOf(&result).Elem(), means)
+	result.Trials = &Trials{Count: len(trials), Fields: estimates}
+	result.Requests = nil
+	for _, trial := range trials {
+		result.Requests = append(result.Requests, trial.Requests...)
+	}
+	return result
+}
+
+// flattenFields collects the numeric fields of v under their JSON paths.
+func flattenFields(prefix string, v reflect.Value, values map[string]float64) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			flattenFields(prefix, v.Elem(), values)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if name := jsonName(v.Type().Field(i)); name != "" {
+				flattenFields(prefix+name, v.Field(i), values)
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			flattenFields(prefix+"."+key.String(), v.MapIndex(key), values)
+		}
+	case reflect.Float64:
+		values[strings.TrimPrefix(prefix, ".")] = v.Float()
+	case reflect.Int:
+		values[strings.TrimPrefix(prefix, ".")] = float64(v.Int())
+	}
+}
+
+// setFields replaces the numeric fields of v with their values from means,
+// copying maps and pointers so v shares no state with the value it came from.
+func setFields(prefix string, v reflect.Value, means map[string]float64) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			copied := reflect.New(v.Elem().Type())
+			copied.Elem().Set(v.Elem())
+			setFields(prefix, copied.Elem(), means)
+			v.Set(copied)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if name := jsonName(v.Type().Field(i)); name != "" {
+				setFields(prefix+name, v.Field(i), means)
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() || v.Type().Elem().Kind() != reflect.Float64 {
+			return
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			value := v.MapIndex(key).Float()
+			if mean, ok := means[strings.TrimPrefix(prefix+"."+key.String(), ".")]; ok {
+				value = roundToTwoDecimals(mean)
+			}
+			copied.SetMapIndex(key, reflect.ValueOf(value))
+		}
+		v.Set(copied)
+	case reflect.Float64:
+		if mean, ok := means[strings.TrimPrefix(prefix, ".")]; ok {
+			v.SetFloat(roundToTwoDecimals(mean))
+		}
+	case reflect.Int:
+		if mean, ok := means[strings.TrimPrefix(prefix, ".")]; ok {
+			v.SetInt(int64(math.Round(mean)))
+		}
+	}
+}
+
+// jsonName returns the path segment of a field, or "" for fields that are not
+// serialised.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" || !field.IsExported() {
+		return ""
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return "." + name
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMeanInterval(t *testing.T) {
+	mean, halfWidth := MeanInterval([]float64{1, 2, 3, 4, 5, 6}, 0.95)
+	// sd = 1.8708, se = 0.7638, t(0.975, 5) = 2.571
+	if mean != 3.5 || math.Abs(halfWidth-1.9634) > 0.001 {
+		t.Errorf("MeanInterval = %v ± %v, want 3.5 ± 1.963", mean, halfWidth)
+	}
+	if _, halfWidth := MeanInterval([]float64{1}, 0.95); halfWidth != 0 {
+		t.Errorf("half-width of one value = %v, want 0", halfWidth)
+	}
+}
+
+func TestAggregateTrials(t *testing.T) {
+	trial := func(genTps float64, ttftP95 float64, stall float64, requests int) SpeedResult {
+		return SpeedResult{
+			Concurrency:     8,
+			GenerationSpeed: genTps,
+			SuccessRate:     1,
+			Ttft:            Distribution{Mean: ttftP95 / 2, Median: ttftP95 / 2, Percentiles: map[string]float64{"p95": ttftP95}},
+			MaxStall:        stall,
+			Goodput:         &Goodput{RequestRate: genTps / 100},
+			Requests:        make([]RequestRecord, requests),
+		}
+	}
+	trials := []SpeedResult{trial(100, 1, 10, 2), trial(110, 2, 20, 3), trial(120, 3, 30, 4)}
+	result := AggregateTrials(trials, 0.95)
+
+	if result.GenerationSpeed != 110 || result.Ttft.Percentiles["p95"] != 2 || result.Ttft.Median != 1 || result.MaxStall != 20 {
+		t.Errorf("means = %v tok/s, p95 %v, median %v, stall %v, want 110, 2, 1, 20",
+			result.GenerationSpeed, result.Ttft.Percentiles["p95"], result.Ttft.Median, result.MaxStall)
+	}
+	if result.Concurrency != 8 || result.SuccessRate != 1 {
+		t.Errorf("constant fields changed to %v, %v", result.Concurrency, result.SuccessRate)
+	}
+	if result.Goodput.RequestRate != 1.1 {
+		t.Errorf("goodput request rate = %v, want 1.1", result.Goodput.RequestRate)
+	}
+	if len(result.Requests) != 9 {
+		t.Errorf("%d requests, want all 9", len(result.Requests))
+	}
+
+	if result.Trials == nil || result.Trials.Count != 3 {
+		t.Fatalf("trials = %+v, want a count of 3", result.Trials)
+	}
+	// sd = 10, t(0.975, 2) = 4.303, half-width 4.303 * 10 / sqrt(3) = 24.84
+	gen := result.Trials.Fields["generation_speed"]
+	if gen.Mean != 110 || gen.Stddev != 10 || gen.CiLow != 85.16 || gen.CiHigh != 134.84 {
+		t.Errorf("generation_speed estimate = %+v, want 110 ± 24.84 with stddev 10", gen)
+	}
+	for _, path := range []string{"ttft.percentiles.p95", "ttft.median", "goodput.request_rate", "max_stall_ms"} {
+		if _, ok := result.Trials.Fields[path]; !ok {
+			t.Errorf("no estimate for %s", path)
+		}
+	}
+
+	// The aggregate shares no maps or pointers with the first trial
+	if trials[0].Ttft.Percentiles["p95"] != 1 || trials[0].Goodput.RequestRate != 1 {
+		t.Error("aggregating changed the first trial")
+	}
+}
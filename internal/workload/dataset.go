@@ -0,0 +1,163 @@
+package workload
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/api"
+)
+
+// Sample is the input of a single request.
+type Sample struct {
+	Prompt    string
+	MaxTokens int // 0 falls back to the benchmark's max tokens
+}
+
+// Source hands out the sample for each request. Implementations must be safe for
+// concurrent use.
+type Source interface {
+	Next() Sample
+}
+
+// shareGPTConversation is one record of a ShareGPT-style dataset.
+type shareGPTConversation struct {
+	Conversations []struct {
+		From  string `json:"from"`
+		Value string `json:"value"`
+	} `json:"conversations"`
+}
+
+// promptRecord is one line of a JSONL prompt dataset.
+type promptRecord struct {
+	Prompt            string `json:"prompt"`
+	ExpectedOutputLen int    `json:"expected_output_len"`
+}
+
+// LoadDataset reads prompts from a local file. Files ending in .jsonl hold one
+// {"prompt", "expected_output_len"} object per line, anything else is parsed as a
+// ShareGPT conversations array, using the first human turn as the prompt and the
+// estimated length of the reply to it as the expected output length.
+func LoadDataset(path string) ([]Sample, error) {
+	var samples []Sample
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+		samples, err = loadPromptLines(path)
+	} else {
+		samples, err = loadShareGPT(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no prompts found in %s", path)
+	}
+	return samples, nil
+}
+
+func loadPromptLines(path string) ([]Sample, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset: %w", err)
+	}
+	defer file.Close()
+
+	var samples []Sample
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var record promptRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if record.Prompt == "" {
+			continue
+		}
+		samples = append(samples, Sample{Prompt: record.Prompt, MaxTokens: record.ExpectedOutputLen})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+	return samples, nil
+}
+
+func loadShareGPT(path string) ([]Sample, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+
+	var records []shareGPTConversation
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse ShareGPT dataset: %w", err)
+	}
+
+	var samples []Sample
+	for _, record := range records {
+		turns := record.Conversations
+		for i, turn := range turns {
+			if turn.From != "human" || turn.Value == "" {
+				continue
+			}
+			sample := Sample{Prompt: turn.Value}
+			if i+1 < len(turns) && turns[i+1].From == "gpt" {
+				sample.MaxTokens = api.EstimateTokens(turns[i+1].Value)
+			}
+			samples = append(samples, sample)
+			break
+		}
+	}
+	return samples, nil
+}
+
+// Dataset samples prompts from a loaded dataset at random.
+type Dataset struct {
+	samples   []Sample
+	maxOutput int
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewDataset returns a Source drawing from samples with a seeded generator, so the
+// same seed yields the same sequence of prompts. A maxOutput above 0 caps each
+// sample's expected output length.
+func NewDataset(samples []Sample, seed int64, maxOutput int) *Dataset {
+	return &Dataset{
+		samples:   samples,
+		maxOutput: maxOutput,
+		rng:       rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (dataset *Dataset) Next() Sample {
+	dataset.mu.Lock()
+	sample := dataset.samples[dataset.rng.Intn(len(dataset.samples))]
+	dataset.mu.Unlock()
+
+	if dataset.maxOutput > 0 && (sample.MaxTokens == 0 || sample.MaxTokens > dataset.maxOutput) {
+		sample.MaxTokens = dataset.maxOutput
+	}
+	return sample
+}
+
+// MeanPromptTokens returns the estimated average prompt length of samples in tokens.
+func MeanPromptTokens(samples []Sample) int {
+	if len(samples) == 0 {
+		return 0
+	}
+	total := 0
+	for _, sample := range samples {
+		total += api.EstimateTokens(sample.Prompt)
+	}
+	return total / len(samples)
+}
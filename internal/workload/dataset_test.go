@@ -0,0 +1,96 @@
+package workload
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/api"
+)
+
+// writeFile writes content to name in a temporary directory and returns its path.
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDataset(t *testing.T) {
+	reply := "The capital of France is Paris."
+	for _, test := range []struct {
+		name    string
+		file    string
+		content string
+		want    []Sample
+		wantErr string
+	}{
+		{
+			name: "jsonl",
+			file: "prompts.jsonl",
+			content: `{"prompt": "Hello", "expected_output_len": 64}
+
+{"prompt": "", "expected_output_len": 8}
+{"prompt": "Bye"}
+`,
+			want: []Sample{{Prompt: "Hello", MaxTokens: 64}, {Prompt: "Bye"}},
+		},
+		{
+			name:    "jsonl with invalid line",
+			file:    "prompts.jsonl",
+			content: "{\"prompt\": \"Hello\"}\nnot json\n",
+			wantErr: "prompts.jsonl:2:",
+		},
+		{
+			name: "sharegpt",
+			file: "sharegpt.json",
+			content: `[
+				{"conversations": [{"from": "human", "value": "Capital of France?"}, {"from": "gpt", "value": "` + reply + `"}]},
+				{"conversations": [{"from": "system", "value": "Be brief."}, {"from": "human", "value": "Hi"}]},
+				{"conversations": [{"from": "gpt", "value": "Only a reply"}]}
+			]`,
+			want: []Sample{{Prompt: "Capital of France?", MaxTokens: api.EstimateTokens(reply)}, {Prompt: "Hi"}},
+		},
+		{
+			name:    "sharegpt without prompts",
+			file:    "empty.json",
+			content: `[{"conversations": []}]`,
+			wantErr: "no prompts found",
+		},
+		{
+			name:    "invalid sharegpt",
+			file:    "broken.json",
+			content: `{"conversations": []}`,
+			wantErr: "failed to parse ShareGPT dataset",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			samples, err := LoadDataset(writeFile(t, test.file, test.content))
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(samples, test.want) {
+				t.Errorf("samples = %+v, want %+v", samples, test.want)
+			}
+		})
+	}
+}
+
+func TestDatasetCapsOutput(t *testing.T) {
+	dataset := NewDataset([]Sample{{Prompt: "a", MaxTokens: 500}, {Prompt: "b"}}, 1, 100)
+	for i := 0; i < 10; i++ {
+		if sample := dataset.Next(); sample.MaxTokens != 100 {
+			t.Errorf("sample %+v not capped at 100 tokens", sample)
+		}
+	}
+}
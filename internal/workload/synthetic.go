@@ -0,0 +1,193 @@
+package workload
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Yoosu-L/llmapibenchmark/internal/api"
+)
+
+// tokensPerWord is the rough number of tokens in each random prompt word.
+const tokensPerWord = 4
+
+// Length is a distribution of token counts.
+type Length interface {
+	// Sample draws a length of at least 1 token.
+	Sample(rng *rand.Rand) int
+}
+
+type fixedLength int
+
+func (length fixedLength) Sample(*rand.Rand) int { return int(length) }
+
+// FixedLength returns a Length that is always tokens long.
+func FixedLength(tokens int) Length {
+	return fixedLength(max(1, tokens))
+}
+
+type uniformLength struct{ min, max int }
+
+func (length uniformLength) Sample(rng *rand.Rand) int {
+	return length.min + rng.Intn(length.max-length.min+1)
+}
+
+type normalLength struct{ mean, stddev float64 }
+
+func (length normalLength) Sample(rng *rand.Rand) int {
+	return clampLength(rng.NormFloat64()*length.stddev + length.mean)
+}
+
+// lognormalLength holds the parameters of the underlying normal distribution.
+// ParseLength derives them from the mean and standard deviation of the lengths.
+type lognormalLength struct{ mu, sigma float64 }
+
+func (length lognormalLength) Sample(rng *rand.Rand) int {
+	return clampLength(math.Exp(rng.NormFloat64()*length.sigma + length.mu))
+}
+
+// empiricalLength draws from a weighted histogram of lengths.
+type empiricalLength struct {
+	lengths    []int
+	cumulative []float64
+}
+
+func (length empiricalLength) Sample(rng *rand.Rand) int {
+	target := rng.Float64() * length.cumulative[len(length.cumulative)-1]
+	return length.lengths[sort.SearchFloat64s(length.cumulative, target)]
+}
+
+func clampLength(value float64) int {
+	return max(1, int(math.Round(value)))
+}
+
+// ParseLength parses a length distribution spec:
+//
+//	fixed:512            always 512 tokens
+//	uniform:128-1024     uniformly between 128 and 1024 tokens
+//	normal:512,128       normal with mean 512 and standard deviation 128
+//	lognormal:512,256    lognormal with mean 512 and standard deviation 256
+//	hist:lengths.txt     empirical histogram, one "tokens [weight]" pair per line
+func ParseLength(spec string) (Length, error) {
+	kind, args, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "fixed":
+		value, err := strconv.Atoi(args)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid fixed length: %s", spec)
+		}
+		return fixedLength(value), nil
+	case "uniform":
+		lowStr, highStr, _ := strings.Cut(args, "-")
+		low, errLow := strconv.Atoi(lowStr)
+		high, errHigh := strconv.Atoi(highStr)
+		if errLow != nil || errHigh != nil || low <= 0 || high < low {
+			return nil, fmt.Errorf("invalid uniform range: %s", spec)
+		}
+		return uniformLength{low, high}, nil
+	case "normal", "lognormal":
+		meanStr, stddevStr, _ := strings.Cut(args, ",")
+		mean, errMean := strconv.ParseFloat(meanStr, 64)
+		stddev, errStddev := strconv.ParseFloat(stddevStr, 64)
+		if errMean != nil || errStddev != nil || mean <= 0 || stddev < 0 {
+			return nil, fmt.Errorf("invalid %s parameters: %s", kind, spec)
+		}
+		if kind == "normal" {
+			return normalLength{mean, stddev}, nil
+		}
+		sigma2 := math.Log(1 + stddev*stddev/(mean*mean))
+		return lognormalLength{mu: math.Log(mean) - sigma2/2, sigma: math.Sqrt(sigma2)}, nil
+	case "hist":
+		return loadHistogram(args)
+	default:
+		return nil, fmt.Errorf("unknown length distribution: %s", spec)
+	}
+}
+
+func loadHistogram(path string) (Length, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open histogram: %w", err)
+	}
+	defer file.Close()
+
+	histogram := empiricalLength{}
+	total := 0.0
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%s:%d: missing length", path, line)
+		}
+		length, err := strconv.Atoi(fields[0])
+		if err != nil || length <= 0 {
+			return nil, fmt.Errorf("%s:%d: invalid length %q", path, line, fields[0])
+		}
+		weight := 1.0
+		if len(fields) > 1 {
+			weight, err = strconv.ParseFloat(fields[1], 64)
+			if err != nil || weight < 0 {
+				return nil, fmt.Errorf("%s:%d: invalid weight %q", path, line, fields[1])
+			}
+		}
+		total += weight
+		histogram.lengths = append(histogram.lengths, length)
+		histogram.cumulative = append(histogram.cumulative, total)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read histogram: %w", err)
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("histogram %s has no weighted lengths", path)
+	}
+	return histogram, nil
+}
+
+// Synthetic generates requests whose input and output lengths follow the given
+// distributions. Inputs are random phrases sized to the sampled length; without an
+// input distribution every request sends Prompt. Without an output distribution
+// the benchmark's max tokens apply.
+type Synthetic struct {
+	input  Length
+	output Length
+	prompt string
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewSynthetic returns a Source drawing lengths with a seeded generator, so the
+// same seed yields the same sequence of requests.
+func NewSynthetic(input Length, output Length, prompt string, seed int64) *Synthetic {
+	return &Synthetic{
+		input:  input,
+		output: output,
+		prompt: prompt,
+		rng:    rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (synthetic *Synthetic) Next() Sample {
+	synthetic.mu.Lock()
+	defer synthetic.mu.Unlock()
+
+	sample := Sample{Prompt: synthetic.prompt}
+	if synthetic.input != nil {
+		numWords := max(1, synthetic.input.Sample(synthetic.rng)/tokensPerWord)
+		sample.Prompt = api.RandomPhrase(synthetic.rng, numWords)
+	}
+	if synthetic.output != nil {
+		sample.MaxTokens = synthetic.output.Sample(synthetic.rng)
+	}
+	return sample
+}
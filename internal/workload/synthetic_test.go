@@ -0,0 +1,119 @@
+package workload
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLength(t *testing.T) {
+	for _, test := range []struct {
+		spec    string
+		want    Length
+		wantErr bool
+	}{
+		{"fixed:512", fixedLength(512), false},
+		{"uniform:128-1024", uniformLength{128, 1024}, false},
+		{"uniform:64-64", uniformLength{64, 64}, false},
+		{"normal:512,128", normalLength{512, 128}, false},
+		{"fixed:0", nil, true},
+		{"fixed:many", nil, true},
+		{"uniform:1024-128", nil, true},
+		{"uniform:0-10", nil, true},
+		{"uniform:128", nil, true},
+		{"normal:512", nil, true},
+		{"normal:-5,1", nil, true},
+		{"lognormal:512,-1", nil, true},
+		{"zipf:1.5", nil, true},
+		{"hist:/does/not/exist", nil, true},
+	} {
+		got, err := ParseLength(test.spec)
+		if (err != nil) != test.wantErr || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseLength(%q) = %#v, %v, want %#v, error %v", test.spec, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestLognormalLengthMean(t *testing.T) {
+	length, err := ParseLength("lognormal:512,256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	total := 0
+	for i := 0; i < 20000; i++ {
+		total += length.Sample(rng)
+	}
+	if mean := float64(total) / 20000; mean < 500 || mean > 524 {
+		t.Errorf("mean of lognormal:512,256 = %v, want about 512", mean)
+	}
+}
+
+func TestLoadHistogram(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+		want    Length
+		wantErr string
+	}{
+		{
+			name:    "weighted",
+			content: "# tokens weight\n128, 3\n\n512\t1\n1024 0\n",
+			want:    empiricalLength{lengths: []int{128, 512, 1024}, cumulative: []float64{3, 4, 4}},
+		},
+		{
+			name:    "unweighted",
+			content: "100\n200\n",
+			want:    empiricalLength{lengths: []int{100, 200}, cumulative: []float64{1, 2}},
+		},
+		{name: "separators only", content: "100\n, ,\n", wantErr: ":2: missing length"},
+		{name: "invalid length", content: "0,1\n", wantErr: ":1: invalid length"},
+		{name: "invalid weight", content: "100,-1\n", wantErr: ":1: invalid weight"},
+		{name: "zero weight", content: "100,0\n", wantErr: "no weighted lengths"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseLength("hist:" + writeFile(t, "lengths.txt", test.content))
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("histogram = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSyntheticIsSeeded(t *testing.T) {
+	draw := func(seed int64) []Sample {
+		synthetic := NewSynthetic(uniformLength{8, 64}, uniformLength{16, 32}, "unused", seed)
+		var samples []Sample
+		for i := 0; i < 5; i++ {
+			samples = append(samples, synthetic.Next())
+		}
+		return samples
+	}
+	first, second := draw(42), draw(42)
+	if !reflect.DeepEqual(first, second) {
+		t.Error("the same seed drew different samples")
+	}
+	for _, sample := range first {
+		if sample.Prompt == "unused" || sample.MaxTokens < 16 || sample.MaxTokens > 32 {
+			t.Errorf("sample %+v doesn't follow the distributions", sample)
+		}
+	}
+	if reflect.DeepEqual(first, draw(43)) {
+		t.Error("different seeds drew the same samples")
+	}
+
+	fixed := NewSynthetic(nil, nil, "Same prompt", 1).Next()
+	if fixed != (Sample{Prompt: "Same prompt"}) {
+		t.Errorf("sample without distributions = %+v, want the plain prompt", fixed)
+	}
+}